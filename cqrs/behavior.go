@@ -0,0 +1,66 @@
+package cqrs
+
+import (
+	"context"
+	"reflect"
+)
+
+// HandlerFunc is the next step of a pipeline: another behavior or, at the end, the handler itself.
+type HandlerFunc func(ctx context.Context, message any) (any, error)
+
+// Behavior wraps the execution of a query or command with cross-cutting logic
+// (logging, validation, timing, transactions, retries...).
+// It may change the context or the message passed to next, short-circuit the call or inspect the result.
+type Behavior func(ctx context.Context, message any, next HandlerFunc) (any, error)
+
+// UseBehavior registers behaviors that wrap every query and command handler.
+// Behaviors run in registration order: the first registered is the outermost.
+func UseBehavior(behaviors ...Behavior) {
+	defaultMediator.UseBehavior(behaviors...)
+}
+
+func (m *Mediator) UseBehavior(behaviors ...Behavior) {
+	m.queries.use(behaviors...)
+	m.commands.use(behaviors...)
+}
+
+// UseQueryBehavior registers behaviors that wrap only query handlers.
+func UseQueryBehavior(behaviors ...Behavior) {
+	defaultMediator.UseQueryBehavior(behaviors...)
+}
+
+func (m *Mediator) UseQueryBehavior(behaviors ...Behavior) {
+	m.queries.use(behaviors...)
+}
+
+// UseCommandBehavior registers behaviors that wrap only command handlers.
+func UseCommandBehavior(behaviors ...Behavior) {
+	defaultMediator.UseCommandBehavior(behaviors...)
+}
+
+func (m *Mediator) UseCommandBehavior(behaviors ...Behavior) {
+	m.commands.use(behaviors...)
+}
+
+// For restricts a behavior to messages of type TMessage (pointer or value).
+// Messages of any other type skip it and go straight to next.
+func For[TMessage any](behavior Behavior) Behavior {
+	messageKey := normalizeType(reflect.TypeFor[TMessage]())
+	return func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+		if normalizeType(reflect.TypeOf(message)) != messageKey {
+			return next(ctx, message)
+		}
+		return behavior(ctx, message, next)
+	}
+}
+
+// chain composes behaviors around the handler, keeping the first behavior as the outermost.
+func chain(behaviors []Behavior, handler HandlerFunc) HandlerFunc {
+	for index := len(behaviors) - 1; index >= 0; index-- {
+		behavior, next := behaviors[index], handler
+		handler = func(ctx context.Context, message any) (any, error) {
+			return behavior(ctx, message, next)
+		}
+	}
+	return handler
+}
@@ -0,0 +1,74 @@
+package cqrs
+
+import (
+	"context"
+	"testing"
+)
+
+type BenchmarkQuery struct{ ID int }
+
+type BenchmarkHandler struct{}
+
+func (h *BenchmarkHandler) Handle(ctx context.Context, q BenchmarkQuery) (int, error) {
+	return q.ID, nil
+}
+
+func newBenchmarkMediator() *Mediator {
+	mediator := New()
+	RegisterQueryHandlerOn[BenchmarkQuery, int, *BenchmarkHandler](mediator, func() *BenchmarkHandler {
+		return &BenchmarkHandler{}
+	})
+	return mediator
+}
+
+func BenchmarkDirectCall(b *testing.B) {
+	ctx := context.Background()
+	var handler IQueryHandler[BenchmarkQuery, int] = &BenchmarkHandler{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := handler.Handle(ctx, BenchmarkQuery{ID: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecuteQuery(b *testing.B) {
+	ctx := context.Background()
+	mediator := newBenchmarkMediator()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ExecuteQueryOn[int](mediator, ctx, BenchmarkQuery{ID: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecuteQuery_PointerCoercion(b *testing.B) {
+	ctx := context.Background()
+	mediator := newBenchmarkMediator()
+	query := &BenchmarkQuery{ID: 1}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ExecuteQueryOn[int](mediator, ctx, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecuteQuery_WithBehavior(b *testing.B) {
+	ctx := context.Background()
+	mediator := newBenchmarkMediator()
+	mediator.UseQueryBehavior(func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+		return next(ctx, message)
+	})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ExecuteQueryOn[int](mediator, ctx, BenchmarkQuery{ID: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type GetPriceQuery struct{ SKU string }
+
+type UpdatePriceCommand struct{ SKU string }
+
+type PriceHandler struct {
+	calls *atomic.Int32
+	delay time.Duration
+}
+
+func (h *PriceHandler) Handle(ctx context.Context, q GetPriceQuery) (int, error) {
+	select {
+	case <-time.After(h.delay):
+		return int(h.calls.Add(1)), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+type UpdatePriceHandler struct{}
+
+func (h *UpdatePriceHandler) Handle(ctx context.Context, c UpdatePriceCommand) (bool, error) {
+	return true, nil
+}
+
+func newPriceMediator(delay time.Duration, policy CachePolicy) (*Mediator, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	mediator := New()
+	RegisterQueryHandlerOn[GetPriceQuery, int, *PriceHandler](mediator, func() *PriceHandler {
+		return &PriceHandler{calls: calls, delay: delay}
+	})
+	RegisterCommandHandlerOn[UpdatePriceCommand, bool, *UpdatePriceHandler](mediator, func() *UpdatePriceHandler {
+		return &UpdatePriceHandler{}
+	})
+	CacheQueryOn[GetPriceQuery](mediator, policy)
+	return mediator, calls
+}
+
+func TestCQRS_QueryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should serve identical queries from the cache", func(t *testing.T) {
+		mediator, calls := newPriceMediator(0, CachePolicy{})
+		for range 3 {
+			if res, _ := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"}); res != 1 {
+				t.Errorf("Expected cached result 1, got %d", res)
+			}
+		}
+		if res, _ := ExecuteQueryOn[int](mediator, ctx, &GetPriceQuery{SKU: "b"}); res != 2 {
+			t.Errorf("Expected a new result for another query, got %d", res)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("Expected 2 handler calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Should expire results after the TTL", func(t *testing.T) {
+		mediator, calls := newPriceMediator(0, CachePolicy{TTL: time.Millisecond})
+		_, _ = ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"})
+		time.Sleep(5 * time.Millisecond)
+		_, _ = ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"})
+		if calls.Load() != 2 {
+			t.Errorf("Expected 2 handler calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Should invalidate when a bound command succeeds", func(t *testing.T) {
+		mediator, calls := newPriceMediator(0, CachePolicy{InvalidatedBy: []any{UpdatePriceCommand{}}})
+		_, _ = ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"})
+		if _, err := ExecuteCommandOn[bool](mediator, ctx, UpdatePriceCommand{SKU: "a"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res, _ := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"}); res != 2 {
+			t.Errorf("Expected a fresh result after invalidation, got %d", res)
+		}
+
+		if err := InvalidateCacheOn[GetPriceQuery](mediator, ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res, _ := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"}); res != 3 {
+			t.Errorf("Expected a fresh result after explicit invalidation, got %d", res)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("Expected 3 handler calls, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Should invalidate across mediators sharing the cache", func(t *testing.T) {
+		shared := NewMemoryCache(16)
+		calls := &atomic.Int32{}
+		newProcess := func() *Mediator {
+			mediator := New(WithCache(shared))
+			RegisterQueryHandlerOn[GetPriceQuery, int, *PriceHandler](mediator, func() *PriceHandler {
+				return &PriceHandler{calls: calls}
+			})
+			RegisterCommandHandlerOn[UpdatePriceCommand, bool, *UpdatePriceHandler](mediator, func() *UpdatePriceHandler {
+				return &UpdatePriceHandler{}
+			})
+			CacheQueryOn[GetPriceQuery](mediator, CachePolicy{InvalidatedBy: []any{UpdatePriceCommand{}}})
+			return mediator
+		}
+		first, second := newProcess(), newProcess()
+
+		_, _ = ExecuteQueryOn[int](first, ctx, GetPriceQuery{SKU: "a"})
+		if res, _ := ExecuteQueryOn[int](second, ctx, GetPriceQuery{SKU: "a"}); res != 1 {
+			t.Errorf("Expected the result cached by the other mediator, got %d", res)
+		}
+		if _, err := ExecuteCommandOn[bool](first, ctx, UpdatePriceCommand{SKU: "a"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res, _ := ExecuteQueryOn[int](second, ctx, GetPriceQuery{SKU: "a"}); res != 2 {
+			t.Errorf("Expected a fresh result after the other mediator invalidated, got %d", res)
+		}
+		// A restarted process reads the current generation instead of serving older entries
+		if res, _ := ExecuteQueryOn[int](newProcess(), ctx, GetPriceQuery{SKU: "a"}); res != 2 {
+			t.Errorf("Expected the current cached result, got %d", res)
+		}
+		// A lost generation orphans the entries written under it
+		_ = shared.Delete(ctx, generationKey(normalizeType(reflect.TypeFor[GetPriceQuery]())))
+		if res, _ := ExecuteQueryOn[int](first, ctx, GetPriceQuery{SKU: "a"}); res != 3 {
+			t.Errorf("Expected a fresh result after the generation was lost, got %d", res)
+		}
+	})
+
+	t.Run("Should invalidate once the whole pipeline succeeded", func(t *testing.T) {
+		mediator, calls := newPriceMediator(0, CachePolicy{InvalidatedBy: []any{UpdatePriceCommand{}}})
+		var inTransaction int
+		rollback := false
+		mediator.UseCommandBehavior(func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+			result, err := next(ctx, message)
+			if err != nil {
+				return nil, err
+			}
+			// A query running before the commit must not fill a new generation with uncommitted data
+			inTransaction, _ = ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"})
+			if rollback {
+				return nil, errors.New("commit failed")
+			}
+			return result, nil
+		})
+		_, _ = ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"})
+
+		if _, err := ExecuteCommandOn[bool](mediator, ctx, UpdatePriceCommand{SKU: "a"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if inTransaction != 1 {
+			t.Errorf("Expected the cache to be invalidated after the behaviors, got %d before the commit", inTransaction)
+		}
+		if res, _ := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"}); res != 2 {
+			t.Errorf("Expected a fresh result after the commit, got %d", res)
+		}
+
+		rollback = true
+		if _, err := ExecuteCommandOn[bool](mediator, ctx, UpdatePriceCommand{SKU: "a"}); err == nil {
+			t.Fatal("Expected the rollback to be reported")
+		}
+		if res, _ := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"}); res != 2 || calls.Load() != 2 {
+			t.Errorf("Expected no invalidation after a rollback, got %d after %d calls", res, calls.Load())
+		}
+	})
+
+	t.Run("Should not fail collapsed queries when the first caller cancels", func(t *testing.T) {
+		mediator, _ := newPriceMediator(50*time.Millisecond, CachePolicy{})
+		firstCtx, cancel := context.WithCancel(ctx)
+
+		first := make(chan error, 1)
+		go func() {
+			_, err := ExecuteQueryOn[int](mediator, firstCtx, GetPriceQuery{SKU: "a"})
+			first <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		second := make(chan error, 1)
+		go func() {
+			_, err := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"})
+			second <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		if err := <-first; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the first caller to stop on its own context, got %v", err)
+		}
+		if err := <-second; err != nil {
+			t.Errorf("Expected the second caller to get the result, got %v", err)
+		}
+	})
+
+	t.Run("Should collapse concurrent identical queries", func(t *testing.T) {
+		mediator, calls := newPriceMediator(20*time.Millisecond, CachePolicy{})
+		var waitGroup sync.WaitGroup
+		for range 10 {
+			waitGroup.Go(func() {
+				if _, err := ExecuteQueryOn[int](mediator, ctx, GetPriceQuery{SKU: "a"}); err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			})
+		}
+		waitGroup.Wait()
+		if calls.Load() != 1 {
+			t.Errorf("Expected 1 handler call, got %d", calls.Load())
+		}
+	})
+}
+
+func TestCQRS_MemoryCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2)
+
+	_ = cache.Set(ctx, "a", 1, 0)
+	_ = cache.Set(ctx, "b", 2, 0)
+	_, _, _ = cache.Get(ctx, "a") // "b" becomes the least recently used
+	_ = cache.Set(ctx, "c", 3, 0)
+
+	if _, found, _ := cache.Get(ctx, "b"); found {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if value, found, _ := cache.Get(ctx, "a"); !found || value != 1 {
+		t.Errorf("Expected 'a' to be kept, got %v", value)
+	}
+	_ = cache.Delete(ctx, "a")
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", cache.Len())
+	}
+}
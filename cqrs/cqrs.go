@@ -1,35 +1,94 @@
 package cqrs
 
-import "context"
+import (
+	"context"
+	"iter"
+)
 
 // --- Queries ---
 
-var queryRegistry = newRegistry("query")
-
 type IQueryHandler[TQuery any, TResult any] interface {
 	Handle(ctx context.Context, query TQuery) (TResult, error)
 }
 
 func RegisterQueryHandler[TQuery any, TResult any, THandler IQueryHandler[TQuery, TResult]](factoryFN any) {
-	register[TQuery, TResult, THandler](queryRegistry, factoryFN)
+	RegisterQueryHandlerOn[TQuery, TResult, THandler](defaultMediator, factoryFN)
+}
+
+func RegisterQueryHandlerOn[TQuery any, TResult any, THandler IQueryHandler[TQuery, TResult]](m *Mediator, factoryFN any) {
+	register[TQuery, TResult, THandler](m.queries, factoryFN)
 }
 
 func ExecuteQuery[TResult any](ctx context.Context, query any) (TResult, error) {
-	return execute[TResult](queryRegistry, ctx, query)
+	return ExecuteQueryOn[TResult](defaultMediator, ctx, query)
 }
 
-// --- Commands ---
+func ExecuteQueryOn[TResult any](m *Mediator, ctx context.Context, query any) (TResult, error) {
+	return execute[TResult](m.queries, ctx, query)
+}
+
+// --- Stream Queries ---
+
+type IStreamQueryHandler[TQuery any, TItem any] interface {
+	Handle(ctx context.Context, query TQuery) iter.Seq2[TItem, error]
+}
+
+func RegisterStreamQueryHandler[TQuery any, TItem any, THandler IStreamQueryHandler[TQuery, TItem]](factoryFN any) {
+	RegisterStreamQueryHandlerOn[TQuery, TItem, THandler](defaultMediator, factoryFN)
+}
+
+func RegisterStreamQueryHandlerOn[TQuery any, TItem any, THandler IStreamQueryHandler[TQuery, TItem]](m *Mediator, factoryFN any) {
+	registerStream[TQuery, TItem, THandler](m.streams, factoryFN)
+}
+
+func ExecuteStream[TItem any](ctx context.Context, query any) iter.Seq2[TItem, error] {
+	return ExecuteStreamOn[TItem](defaultMediator, ctx, query)
+}
+
+func ExecuteStreamOn[TItem any](m *Mediator, ctx context.Context, query any) iter.Seq2[TItem, error] {
+	return executeStream[TItem](m.streams, ctx, query)
+}
 
-var commandRegistry = newRegistry("command")
+// --- Commands ---
 
 type ICommandHandler[TCommand any, TResult any] interface {
 	Handle(ctx context.Context, command TCommand) (TResult, error)
 }
 
 func RegisterCommandHandler[TCommand any, TResult any, THandler ICommandHandler[TCommand, TResult]](factoryFN any) {
-	register[TCommand, TResult, THandler](commandRegistry, factoryFN)
+	RegisterCommandHandlerOn[TCommand, TResult, THandler](defaultMediator, factoryFN)
+}
+
+func RegisterCommandHandlerOn[TCommand any, TResult any, THandler ICommandHandler[TCommand, TResult]](m *Mediator, factoryFN any) {
+	register[TCommand, TResult, THandler](m.commands, factoryFN)
 }
 
 func ExecuteCommand[TResult any](ctx context.Context, command any) (TResult, error) {
-	return execute[TResult](commandRegistry, ctx, command)
+	return ExecuteCommandOn[TResult](defaultMediator, ctx, command)
+}
+
+func ExecuteCommandOn[TResult any](m *Mediator, ctx context.Context, command any) (TResult, error) {
+	return execute[TResult](m.commands, ctx, command)
+}
+
+// --- Events ---
+
+type IEventHandler[TEvent any] interface {
+	Handle(ctx context.Context, event TEvent) error
+}
+
+func RegisterEventHandler[TEvent any, THandler IEventHandler[TEvent]](factoryFN any) {
+	RegisterEventHandlerOn[TEvent, THandler](defaultMediator, factoryFN)
+}
+
+func RegisterEventHandlerOn[TEvent any, THandler IEventHandler[TEvent]](m *Mediator, factoryFN any) {
+	subscribe[TEvent, THandler](m.events, factoryFN)
+}
+
+func Publish(ctx context.Context, event any, strategy ...PublishStrategy) error {
+	return defaultMediator.Publish(ctx, event, strategy...)
+}
+
+func (m *Mediator) Publish(ctx context.Context, event any, strategy ...PublishStrategy) error {
+	return publish(m.events, ctx, event, strategy...)
 }
@@ -3,9 +3,18 @@ package cqrs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/leandroluk/go/di"
+	"github.com/leandroluk/go/v"
 )
 
 // --- Mocks ---
@@ -58,3 +67,646 @@ func TestCQRS_FullFlow(t *testing.T) {
 		}
 	})
 }
+
+type BehaviorCommand struct{ Value int }
+
+type BehaviorHandler struct{}
+
+func (h *BehaviorHandler) Handle(ctx context.Context, c BehaviorCommand) (int, error) {
+	return c.Value * 10, nil
+}
+
+func TestCQRS_Behaviors(t *testing.T) {
+	di.Reset()
+	ctx := context.Background()
+
+	RegisterCommandHandler[BehaviorCommand, int, *BehaviorHandler](func() *BehaviorHandler {
+		return &BehaviorHandler{}
+	})
+
+	var calls []string
+	trace := func(name string) Behavior {
+		return func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+			calls = append(calls, name+":before")
+			result, err := next(ctx, message)
+			calls = append(calls, name+":after")
+			return result, err
+		}
+	}
+
+	UseCommandBehavior(For[BehaviorCommand](trace("outer")), For[BehaviorCommand](trace("inner")))
+	UseQueryBehavior(For[BehaviorCommand](trace("query")))
+
+	t.Run("Should run behaviors in registration order", func(t *testing.T) {
+		calls = nil
+		res, err := ExecuteCommand[int](ctx, BehaviorCommand{Value: 2})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res != 20 {
+			t.Errorf("Expected 20, got %d", res)
+		}
+		expected := []string{"outer:before", "inner:before", "inner:after", "outer:after"}
+		if !slices.Equal(calls, expected) {
+			t.Errorf("Expected %v, got %v", expected, calls)
+		}
+	})
+
+	t.Run("Should allow behaviors to replace the message", func(t *testing.T) {
+		UseBehavior(For[BehaviorCommand](func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+			return next(ctx, &BehaviorCommand{Value: 5})
+		}))
+		res, err := ExecuteCommand[int](ctx, BehaviorCommand{Value: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res != 50 {
+			t.Errorf("Expected 50, got %d", res)
+		}
+	})
+
+	t.Run("Should short-circuit with an error", func(t *testing.T) {
+		UseCommandBehavior(For[*BehaviorCommand](func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+			return nil, errors.New("denied")
+		}))
+		_, err := ExecuteCommand[int](ctx, BehaviorCommand{Value: 1})
+		if err == nil || err.Error() != "denied" {
+			t.Errorf("Expected 'denied' error, got %v", err)
+		}
+	})
+}
+
+type UserCreatedEvent struct{ ID int }
+
+type EmailHandler struct{}
+
+func (h *EmailHandler) Handle(ctx context.Context, e UserCreatedEvent) error {
+	if e.ID == 0 {
+		return errors.New("email failed")
+	}
+	return nil
+}
+
+type AuditHandler struct{ calls *atomic.Int32 }
+
+func (h *AuditHandler) Handle(ctx context.Context, e *UserCreatedEvent) error {
+	h.calls.Add(1)
+	if e.ID == 0 {
+		return errors.New("audit failed")
+	}
+	return nil
+}
+
+func TestCQRS_Events(t *testing.T) {
+	di.Reset()
+	ctx := context.Background()
+	auditCalls := &atomic.Int32{}
+
+	RegisterEventHandler[UserCreatedEvent, *EmailHandler](func() *EmailHandler {
+		return &EmailHandler{}
+	})
+	RegisterEventHandler[*UserCreatedEvent, *AuditHandler](func() *AuditHandler {
+		return &AuditHandler{calls: auditCalls}
+	})
+
+	t.Run("Should fan out to every handler", func(t *testing.T) {
+		auditCalls.Store(0)
+		for _, strategy := range []PublishStrategy{Sequential, Parallel, CollectAll} {
+			if err := Publish(ctx, UserCreatedEvent{ID: 1}, strategy); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+		if auditCalls.Load() != 3 {
+			t.Errorf("Expected 3 audit calls, got %d", auditCalls.Load())
+		}
+	})
+
+	t.Run("Should stop at the first error when sequential", func(t *testing.T) {
+		auditCalls.Store(0)
+		err := Publish(ctx, &UserCreatedEvent{ID: 0})
+		if err == nil || err.Error() != "email failed" {
+			t.Errorf("Expected 'email failed' error, got %v", err)
+		}
+		if auditCalls.Load() != 0 {
+			t.Errorf("Expected audit handler to be skipped, got %d calls", auditCalls.Load())
+		}
+	})
+
+	t.Run("Should aggregate errors when collecting all", func(t *testing.T) {
+		for _, strategy := range []PublishStrategy{Parallel, CollectAll} {
+			err := Publish(ctx, UserCreatedEvent{ID: 0}, strategy)
+			if err == nil || err.Error() != "email failed\naudit failed" {
+				t.Errorf("Expected both errors, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Should ignore events without handlers", func(t *testing.T) {
+		if err := Publish(ctx, TestQuery{ID: 1}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should panic on duplicate handler", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Should have panicked when registering the same handler twice")
+			}
+		}()
+		RegisterEventHandler[UserCreatedEvent, *EmailHandler](func() *EmailHandler {
+			return &EmailHandler{}
+		})
+	})
+}
+
+type GreetingHandler struct{ greeting string }
+
+func (h *GreetingHandler) Handle(ctx context.Context, q TestQuery) (TestResponse, error) {
+	return TestResponse{Name: h.greeting}, nil
+}
+
+func TestCQRS_Mediator(t *testing.T) {
+	ctx := context.Background()
+
+	for _, greeting := range []string{"hello", "olá"} {
+		t.Run("Should isolate registries for "+greeting, func(t *testing.T) {
+			t.Parallel()
+			mediator := New()
+			RegisterQueryHandlerOn[TestQuery, TestResponse, *GreetingHandler](mediator, func() *GreetingHandler {
+				return &GreetingHandler{greeting: greeting}
+			})
+
+			res, err := ExecuteQueryOn[TestResponse](mediator, ctx, TestQuery{ID: 1})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Name != greeting {
+				t.Errorf("Expected %q, got %q", greeting, res.Name)
+			}
+		})
+	}
+
+	t.Run("Should use the given container", func(t *testing.T) {
+		container := di.New()
+		mediator := New(WithContainer(container))
+		RegisterCommandHandlerOn[BehaviorCommand, int, *BehaviorHandler](mediator, func() *BehaviorHandler {
+			return &BehaviorHandler{}
+		})
+
+		if mediator.Container() != container {
+			t.Fatal("Expected mediator to keep the given container")
+		}
+		if handler := di.ResolveOn[*BehaviorHandler](container); handler == nil {
+			t.Error("Expected handler to be registered in the given container")
+		}
+		if _, err := ExecuteQueryOn[int](mediator, ctx, BehaviorCommand{Value: 1}); err == nil {
+			t.Error("Expected error when executing a command as a query")
+		}
+	})
+
+	t.Run("Should wrap the default mediator", func(t *testing.T) {
+		if Default().Container() != di.Default() {
+			t.Error("Expected default mediator to use the default container")
+		}
+	})
+}
+
+type ExportQuery struct{ Count int }
+
+type ExportRow struct{ Index int }
+
+type ExportHandler struct{}
+
+func (h *ExportHandler) Handle(ctx context.Context, q ExportQuery) iter.Seq2[*ExportRow, error] {
+	return func(yield func(*ExportRow, error) bool) {
+		for index := range q.Count {
+			if index == 3 {
+				if !yield(nil, errors.New("row failed")) {
+					return
+				}
+				continue
+			}
+			if !yield(&ExportRow{Index: index}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestCQRS_Stream(t *testing.T) {
+	mediator := New()
+	RegisterStreamQueryHandlerOn[ExportQuery, *ExportRow, *ExportHandler](mediator, func() *ExportHandler {
+		return &ExportHandler{}
+	})
+
+	t.Run("Should stream coerced items and errors", func(t *testing.T) {
+		var indexes []int
+		var failures int
+		for row, err := range ExecuteStreamOn[ExportRow](mediator, context.Background(), &ExportQuery{Count: 5}) {
+			if err != nil {
+				failures++
+				continue
+			}
+			indexes = append(indexes, row.Index)
+		}
+		if !slices.Equal(indexes, []int{0, 1, 2, 4}) {
+			t.Errorf("Expected [0 1 2 4], got %v", indexes)
+		}
+		if failures != 1 {
+			t.Errorf("Expected 1 failure, got %d", failures)
+		}
+	})
+
+	t.Run("Should stop on context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var received int
+		var lastErr error
+		for _, err := range ExecuteStreamOn[*ExportRow](mediator, ctx, ExportQuery{Count: 100}) {
+			if err != nil {
+				lastErr = err
+				break
+			}
+			received++
+			if received == 2 {
+				cancel()
+			}
+		}
+		if received != 2 || !errors.Is(lastErr, context.Canceled) {
+			t.Errorf("Expected 2 items then context.Canceled, got %d items and %v", received, lastErr)
+		}
+	})
+
+	t.Run("Should yield dispatch errors", func(t *testing.T) {
+		for _, err := range ExecuteStreamOn[ExportRow](mediator, context.Background(), TestQuery{}) {
+			if err == nil {
+				t.Error("Expected error for a query without stream handler")
+			}
+		}
+	})
+}
+
+type CreateProductCommand struct{ Name string }
+
+type CreateProductHandler struct{}
+
+func (h *CreateProductHandler) Handle(ctx context.Context, c *CreateProductCommand) (string, error) {
+	return c.Name, nil
+}
+
+func TestCQRS_Validation(t *testing.T) {
+	ctx := context.Background()
+	v.Object(func(c *CreateProductCommand, s *v.ObjectSchema[CreateProductCommand]) {
+		s.Field(&c.Name).Text().Required().Min(3).Transform(func(value any) (any, error) {
+			return strings.TrimSpace(value.(string)), nil
+		})
+	})
+
+	newMediator := func(options ...Option) *Mediator {
+		mediator := New(options...)
+		RegisterCommandHandlerOn[*CreateProductCommand, string, *CreateProductHandler](mediator, func() *CreateProductHandler {
+			return &CreateProductHandler{}
+		})
+		RegisterQueryHandlerOn[TestQuery, TestResponse, *TestHandler](mediator, func() *TestHandler {
+			return &TestHandler{}
+		})
+		return mediator
+	}
+
+	t.Run("Should skip validation unless enabled", func(t *testing.T) {
+		res, err := ExecuteCommandOn[string](newMediator(), ctx, CreateProductCommand{Name: "x"})
+		if err != nil || res != "x" {
+			t.Errorf("Expected 'x' without error, got %q and %v", res, err)
+		}
+	})
+
+	t.Run("Should reject invalid messages per mediator", func(t *testing.T) {
+		mediator := newMediator(WithValidation())
+		_, err := ExecuteCommandOn[string](mediator, ctx, CreateProductCommand{Name: "x"})
+		var validationErr v.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected v.ValidationError, got %v", err)
+		}
+
+		if _, err := ExecuteQueryOn[TestResponse](mediator, ctx, TestQuery{ID: 1}); err != nil {
+			t.Errorf("Expected messages without schema to pass, got %v", err)
+		}
+	})
+
+	t.Run("Should hand the validated output to the handler", func(t *testing.T) {
+		mediator := newMediator()
+		EnableValidationOn[CreateProductCommand](mediator)
+		res, err := ExecuteCommandOn[string](mediator, ctx, &CreateProductCommand{Name: "  chair  "})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if res != "chair" {
+			t.Errorf("Expected the transformed name, got %q", res)
+		}
+	})
+
+	t.Run("Should require a schema when enabled per type", func(t *testing.T) {
+		mediator := newMediator()
+		EnableValidationOn[TestQuery](mediator)
+		_, err := ExecuteQueryOn[TestResponse](mediator, ctx, TestQuery{ID: 1})
+		if !errors.Is(err, v.ErrNoSchema) {
+			t.Errorf("Expected v.ErrNoSchema, got %v", err)
+		}
+	})
+}
+
+type ChargeCommand struct{ Amount int }
+
+type ChargeHandler struct {
+	attempts map[int]int
+	mutex    *sync.Mutex
+	done     chan int
+}
+
+func (h *ChargeHandler) Handle(ctx context.Context, c ChargeCommand) (bool, error) {
+	h.mutex.Lock()
+	h.attempts[c.Amount]++
+	attempts := h.attempts[c.Amount]
+	h.mutex.Unlock()
+
+	// Negative amounts always fail, odd amounts fail once
+	if c.Amount < 0 || (c.Amount%2 == 1 && attempts == 1) {
+		return false, fmt.Errorf("charge %d failed", c.Amount)
+	}
+	h.done <- c.Amount
+	return true, nil
+}
+
+func TestCQRS_Send(t *testing.T) {
+	ctx := context.Background()
+	handler := &ChargeHandler{attempts: map[int]int{}, mutex: &sync.Mutex{}, done: make(chan int, 10)}
+	deadLetters := make(chan Envelope, 1)
+
+	mediator := New(
+		WithOutbox(NewMemoryOutbox(10)),
+		WithWorkers(3),
+		WithRetry(2, ExponentialBackoff(time.Millisecond, 10*time.Millisecond)),
+		WithDeadLetter(func(ctx context.Context, envelope Envelope, err error) {
+			deadLetters <- envelope
+		}),
+	)
+	RegisterCommandHandlerOn[ChargeCommand, bool, *ChargeHandler](mediator, func() *ChargeHandler {
+		return handler
+	})
+
+	t.Run("Should execute sent commands with retries", func(t *testing.T) {
+		for amount := 1; amount <= 4; amount++ {
+			if err := mediator.Send(ctx, ChargeCommand{Amount: amount}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+		var done []int
+		for range 4 {
+			select {
+			case amount := <-handler.done:
+				done = append(done, amount)
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out, executed %v", done)
+			}
+		}
+		slices.Sort(done)
+		if !slices.Equal(done, []int{1, 2, 3, 4}) {
+			t.Errorf("Expected [1 2 3 4], got %v", done)
+		}
+	})
+
+	t.Run("Should send exhausted commands to the dead letter", func(t *testing.T) {
+		if err := mediator.Send(ctx, &ChargeCommand{Amount: -1}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		select {
+		case envelope := <-deadLetters:
+			if envelope.Attempts != 2 || envelope.ID == "" {
+				t.Errorf("Expected 2 attempts and an ID, got %+v", envelope)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the dead letter")
+		}
+	})
+
+	t.Run("Should fail fast without handler", func(t *testing.T) {
+		if err := mediator.Send(ctx, TestQuery{ID: 1}); err == nil {
+			t.Error("Expected error for a command without handler")
+		}
+	})
+
+	t.Run("Should stop accepting commands after shutdown", func(t *testing.T) {
+		if err := mediator.Shutdown(ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := mediator.Send(ctx, ChargeCommand{Amount: 2}); !errors.Is(err, ErrDispatcherStopped) {
+			t.Errorf("Expected ErrDispatcherStopped, got %v", err)
+		}
+	})
+}
+
+// persistentOutbox stands for an outbox backed by a database, whose pending commands survive Shutdown.
+type persistentOutbox struct{ *MemoryOutbox }
+
+func TestCQRS_ShutdownDuringRetry(t *testing.T) {
+	ctx := context.Background()
+	handler := &ChargeHandler{attempts: map[int]int{}, mutex: &sync.Mutex{}, done: make(chan int, 1)}
+	outbox := persistentOutbox{NewMemoryOutbox(10)}
+	deadLetters := make(chan Envelope, 1)
+
+	mediator := New(
+		WithOutbox(outbox),
+		WithWorkers(1),
+		WithRetry(3, func(attempt int) time.Duration { return time.Hour }),
+		WithDeadLetter(func(ctx context.Context, envelope Envelope, err error) {
+			deadLetters <- envelope
+		}),
+	)
+	RegisterCommandHandlerOn[ChargeCommand, bool, *ChargeHandler](mediator, func() *ChargeHandler {
+		return handler
+	})
+
+	if err := mediator.Send(ctx, ChargeCommand{Amount: 1}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Wait for the first attempt to fail, leaving the command waiting for its retry
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		handler.mutex.Lock()
+		attempts := handler.attempts[1]
+		handler.mutex.Unlock()
+		if attempts == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the first attempt")
+		}
+	}
+
+	if err := mediator.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case envelope := <-deadLetters:
+		t.Fatalf("Expected no dead letter, got %+v", envelope)
+	default:
+	}
+	if outbox.Len() != 1 {
+		t.Fatalf("Expected the command back in the outbox, got %d pending", outbox.Len())
+	}
+	envelope, _ := outbox.Dequeue(ctx)
+	if envelope.Attempts != 1 || envelope.ID == "" {
+		t.Errorf("Expected 1 attempt and an ID, got %+v", envelope)
+	}
+}
+
+type SlowCommand struct{ ID int }
+
+type SlowHandler struct {
+	started chan int
+	release chan struct{}
+}
+
+func (h *SlowHandler) Handle(ctx context.Context, c SlowCommand) (bool, error) {
+	h.started <- c.ID
+	<-h.release
+	return true, nil
+}
+
+func TestCQRS_ShutdownWithFullOutbox(t *testing.T) {
+	ctx := context.Background()
+	handler := &SlowHandler{started: make(chan int, 3), release: make(chan struct{})}
+	deadLetters := make(chan error, 3)
+
+	mediator := New(
+		WithOutbox(NewMemoryOutbox(1)),
+		WithWorkers(1),
+		WithDeadLetter(func(ctx context.Context, envelope Envelope, err error) {
+			deadLetters <- err
+		}),
+	)
+	RegisterCommandHandlerOn[SlowCommand, bool, *SlowHandler](mediator, func() *SlowHandler {
+		return handler
+	})
+
+	// The worker runs the first command and the second fills the outbox, so the third Send blocks
+	_ = mediator.Send(ctx, SlowCommand{ID: 1})
+	<-handler.started
+	_ = mediator.Send(ctx, SlowCommand{ID: 2})
+	blocked := make(chan error, 1)
+	go func() { blocked <- mediator.Send(ctx, SlowCommand{ID: 3}) }()
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- mediator.Shutdown(ctx) }()
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrDispatcherStopped) {
+			t.Errorf("Expected the blocked Send to fail with ErrDispatcherStopped, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Shutdown to cancel the blocked Send")
+	}
+
+	close(handler.release)
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The second command either ran before the workers stopped, or was left in the memory outbox
+	// and went to the dead letter instead of being lost
+	executed := len(handler.started)
+	if executed+len(deadLetters) != 1 {
+		t.Fatalf("Expected the second command to be executed or dead-lettered once, got %d executions and %d dead letters", executed, len(deadLetters))
+	}
+	if executed == 0 {
+		if err := <-deadLetters; !errors.Is(err, ErrDispatcherStopped) {
+			t.Errorf("Expected ErrDispatcherStopped, got %v", err)
+		}
+	}
+}
+
+func TestCQRS_SendAfterShutdownBeforeStart(t *testing.T) {
+	mediator := New()
+	RegisterCommandHandlerOn[ChargeCommand, bool, *ChargeHandler](mediator, func() *ChargeHandler {
+		return &ChargeHandler{}
+	})
+
+	if err := mediator.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := mediator.Send(context.Background(), ChargeCommand{Amount: 2}); !errors.Is(err, ErrDispatcherStopped) {
+		t.Errorf("Expected ErrDispatcherStopped, got %v", err)
+	}
+}
+
+func TestCQRS_ExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for index, delay := range expected {
+		if got := backoff(index + 1); got != delay {
+			t.Errorf("Attempt %d: expected %v, got %v", index+1, delay, got)
+		}
+	}
+}
+
+func TestCQRS_FailingHandlerFactory(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	mediator := New()
+	RegisterQueryHandlerOn[TestQuery, TestResponse, *TestHandler](mediator, func() (*TestHandler, error) {
+		return nil, boom
+	})
+	RegisterEventHandlerOn[UserCreatedEvent, *EmailHandler](mediator, func() (*EmailHandler, error) {
+		return nil, boom
+	})
+	RegisterStreamQueryHandlerOn[ExportQuery, *ExportRow, *ExportHandler](mediator, func() (*ExportHandler, error) {
+		return nil, boom
+	})
+
+	t.Run("Should return the error from queries and commands", func(t *testing.T) {
+		if _, err := ExecuteQueryOn[TestResponse](mediator, ctx, TestQuery{ID: 1}); !errors.Is(err, boom) {
+			t.Errorf("Expected the factory error, got %v", err)
+		}
+	})
+
+	t.Run("Should return the error from event handlers", func(t *testing.T) {
+		if err := mediator.Publish(ctx, UserCreatedEvent{ID: 1}); !errors.Is(err, boom) {
+			t.Errorf("Expected the factory error, got %v", err)
+		}
+	})
+
+	t.Run("Should yield the error from streams", func(t *testing.T) {
+		for _, err := range ExecuteStreamOn[*ExportRow](mediator, ctx, ExportQuery{Count: 1}) {
+			if !errors.Is(err, boom) {
+				t.Errorf("Expected the factory error, got %v", err)
+			}
+		}
+	})
+}
+
+func TestCQRS_Handlers(t *testing.T) {
+	mediator := New()
+	RegisterCommandHandlerOn[BehaviorCommand, int, *BehaviorHandler](mediator, func() *BehaviorHandler {
+		return &BehaviorHandler{}
+	})
+	RegisterQueryHandlerOn[TestQuery, TestResponse, *TestHandler](mediator, func() *TestHandler {
+		return &TestHandler{}
+	})
+	RegisterStreamQueryHandlerOn[ExportQuery, *ExportRow, *ExportHandler](mediator, func() *ExportHandler {
+		return &ExportHandler{}
+	})
+
+	expected := []HandlerDescriptor{
+		{KindCommand, reflect.TypeFor[BehaviorCommand](), reflect.TypeFor[int](), reflect.TypeFor[*BehaviorHandler]()},
+		{KindQuery, reflect.TypeFor[TestQuery](), reflect.TypeFor[TestResponse](), reflect.TypeFor[*TestHandler]()},
+		{KindStreamQuery, reflect.TypeFor[ExportQuery](), reflect.TypeFor[*ExportRow](), reflect.TypeFor[*ExportHandler]()},
+	}
+	if got := mediator.Handlers(); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if got := New().Handlers(); len(got) != 0 {
+		t.Errorf("Expected no handlers, got %v", got)
+	}
+}
@@ -0,0 +1,277 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrDispatcherStopped is returned by Send after Shutdown.
+var ErrDispatcherStopped = errors.New("cqrs: dispatcher stopped")
+
+// DeadLetterFunc receives commands that failed on every attempt.
+type DeadLetterFunc func(ctx context.Context, envelope Envelope, err error)
+
+// BackoffFunc returns how long to wait before the given retry attempt (starting at 1).
+type BackoffFunc func(attempt int) time.Duration
+
+// ExponentialBackoff doubles the delay on every attempt, starting at base and capped at limit.
+func ExponentialBackoff(base time.Duration, limit time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		delay := base
+		for range attempt - 1 {
+			delay *= 2
+			if delay >= limit {
+				return limit
+			}
+		}
+		return min(delay, limit)
+	}
+}
+
+// dispatcher executes sent commands in the background with a pool of workers.
+// Workers start on the first Send and stop on Shutdown.
+type dispatcher struct {
+	commands    *registry
+	outbox      IOutbox
+	workers     int
+	maxAttempts int
+	backoff     BackoffFunc
+	deadLetter  DeadLetterFunc
+
+	mutex         sync.Mutex
+	stopped       bool
+	stop          context.CancelFunc
+	stopping      context.Context // Done when Shutdown starts, cancelling the Sends in flight.
+	cancelSending context.CancelFunc
+	shutdownCtx   context.Context // The context given to Shutdown, bounding how long interrupted retries wait to be requeued.
+	sending       sync.WaitGroup  // Sends past the stopped check, until their envelope is in the outbox.
+	waitGroup     sync.WaitGroup
+}
+
+func newDispatcher() *dispatcher {
+	stopping, cancelSending := context.WithCancel(context.Background())
+	return &dispatcher{
+		outbox:        NewMemoryOutbox(1024),
+		workers:       runtime.NumCPU(),
+		maxAttempts:   3,
+		backoff:       ExponentialBackoff(100*time.Millisecond, 5*time.Second),
+		stopping:      stopping,
+		cancelSending: cancelSending,
+	}
+}
+
+// WithOutbox sets where sent commands wait for a worker. Defaults to NewMemoryOutbox(1024).
+func WithOutbox(outbox IOutbox) Option {
+	return func(mediator *Mediator) {
+		mediator.dispatcher.outbox = outbox
+	}
+}
+
+// WithWorkers sets how many commands are executed concurrently in the background. Defaults to runtime.NumCPU().
+func WithWorkers(workers int) Option {
+	return func(mediator *Mediator) {
+		mediator.dispatcher.workers = max(workers, 1)
+	}
+}
+
+// WithRetry sets how many times a sent command is attempted and the delay between attempts.
+// Defaults to 3 attempts with ExponentialBackoff(100ms, 5s).
+func WithRetry(maxAttempts int, backoff BackoffFunc) Option {
+	return func(mediator *Mediator) {
+		mediator.dispatcher.maxAttempts = max(maxAttempts, 1)
+		mediator.dispatcher.backoff = backoff
+	}
+}
+
+// WithDeadLetter sets the sink for commands that failed on every attempt. By default they are dropped.
+func WithDeadLetter(deadLetter DeadLetterFunc) Option {
+	return func(mediator *Mediator) {
+		mediator.dispatcher.deadLetter = deadLetter
+	}
+}
+
+// Send enqueues a command to be executed asynchronously by the default mediator.
+func Send(ctx context.Context, command any) error {
+	return defaultMediator.Send(ctx, command)
+}
+
+// Send enqueues a command to be executed asynchronously. The result of the handler is discarded.
+// It fails fast when no handler is registered for the command type.
+func (m *Mediator) Send(ctx context.Context, command any) error {
+	return m.dispatcher.send(ctx, command)
+}
+
+// Shutdown stops the workers of the default mediator.
+func Shutdown(ctx context.Context) error {
+	return defaultMediator.Shutdown(ctx)
+}
+
+// Shutdown stops accepting commands, cancels the Sends still waiting for room in the outbox (they return
+// ErrDispatcherStopped) and waits for the workers to finish the commands they are executing.
+// Commands waiting to be retried are put back in the outbox with their attempts so far. What is left in
+// a persistent outbox stays there for the next run; what is left in a MemoryOutbox, which nothing could
+// ever execute, is handed to the dead letter with ErrDispatcherStopped.
+func (m *Mediator) Shutdown(ctx context.Context) error {
+	return m.dispatcher.shutdown(ctx)
+}
+
+func (d *dispatcher) send(ctx context.Context, command any) error {
+	messageKey, err := normalizedTypeKeyOfValue(command, d.commands.kindName)
+	if err != nil {
+		return err
+	}
+
+	d.commands.mutex.RLock()
+	_, exists := d.commands.executors[messageKey]
+	d.commands.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("cqrs: no %s handler registered for type %v", d.commands.kindName, messageKey)
+	}
+
+	d.mutex.Lock()
+	if d.stopped {
+		d.mutex.Unlock()
+		return ErrDispatcherStopped
+	}
+	if d.stop == nil {
+		d.start()
+	}
+	d.sending.Add(1)
+	d.mutex.Unlock()
+	defer d.sending.Done()
+
+	// Shutdown cancels the Sends blocked on a full outbox rather than waiting for room that may never come
+	enqueueCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(d.stopping, cancel)()
+
+	err = d.outbox.Enqueue(enqueueCtx, Envelope{
+		ID:         newEnvelopeID(),
+		Command:    command,
+		EnqueuedAt: time.Now(),
+	})
+	if err != nil && ctx.Err() == nil && d.stopping.Err() != nil {
+		return ErrDispatcherStopped
+	}
+	return err
+}
+
+// start launches the workers. Called with the mutex held.
+func (d *dispatcher) start() {
+	stopCtx, stop := context.WithCancel(context.Background())
+	d.stop = stop
+
+	for range d.workers {
+		d.waitGroup.Go(func() {
+			d.work(stopCtx)
+		})
+	}
+}
+
+func (d *dispatcher) work(stopCtx context.Context) {
+	for stopCtx.Err() == nil {
+		envelope, err := d.outbox.Dequeue(stopCtx)
+		if err != nil {
+			return
+		}
+		// Dequeue may return an envelope even though Shutdown was called meanwhile
+		if stopCtx.Err() != nil {
+			d.requeue(envelope)
+			return
+		}
+		d.process(stopCtx, envelope)
+	}
+}
+
+// process executes the command, retrying with backoff, and hands it to the dead letter after the last attempt.
+// The handler runs with its own context, so Shutdown never interrupts a command halfway; a command
+// waiting for its next attempt when Shutdown is called was not exhausted, so it goes back to the outbox.
+func (d *dispatcher) process(stopCtx context.Context, envelope Envelope) {
+	executionCtx := context.Background()
+
+	var lastErr error
+	for envelope.Attempts < d.maxAttempts {
+		if envelope.Attempts > 0 && d.backoff != nil {
+			select {
+			case <-time.After(d.backoff(envelope.Attempts)):
+			case <-stopCtx.Done():
+				d.requeue(envelope)
+				return
+			}
+		}
+
+		envelope.Attempts++
+		if _, lastErr = dispatch(d.commands, executionCtx, envelope.Command); lastErr == nil {
+			_ = d.outbox.Ack(executionCtx, envelope)
+			return
+		}
+	}
+
+	d.fail(executionCtx, envelope, lastErr)
+}
+
+func (d *dispatcher) fail(ctx context.Context, envelope Envelope, err error) {
+	if d.deadLetter != nil {
+		d.deadLetter(ctx, envelope, err)
+	}
+	_ = d.outbox.Ack(ctx, envelope)
+}
+
+// requeue puts an envelope whose retries were interrupted by Shutdown back in the outbox, without acking it.
+func (d *dispatcher) requeue(envelope Envelope) {
+	d.mutex.Lock()
+	ctx := d.shutdownCtx
+	d.mutex.Unlock()
+
+	_ = d.outbox.Enqueue(ctx, envelope)
+}
+
+func (d *dispatcher) shutdown(ctx context.Context) error {
+	d.mutex.Lock()
+	d.stopped = true
+	d.shutdownCtx = ctx
+	stop := d.stop
+	d.mutex.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+
+	// Every Send either fails or has its envelope in the outbox before the workers stop
+	d.cancelSending()
+	if err := wait(ctx, &d.sending); err != nil {
+		return err
+	}
+	stop()
+	if err := wait(ctx, &d.waitGroup); err != nil {
+		return err
+	}
+
+	if memoryOutbox, ok := d.outbox.(*MemoryOutbox); ok {
+		for _, envelope := range memoryOutbox.drain() {
+			d.fail(ctx, envelope, ErrDispatcherStopped)
+		}
+	}
+	return nil
+}
+
+// wait waits for the group, giving up when ctx is done.
+func wait(ctx context.Context, waitGroup *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		waitGroup.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/leandroluk/go/di"
+)
+
+// PublishStrategy defines how an event is delivered to its handlers.
+type PublishStrategy int
+
+const (
+	// Sequential calls handlers one by one in registration order and stops at the first error.
+	Sequential PublishStrategy = iota
+	// Parallel calls all handlers concurrently and waits for every one of them.
+	Parallel
+	// CollectAll calls handlers one by one in registration order, even after a failure.
+	CollectAll
+)
+
+type eventHandler struct {
+	identity any // The handler type, or the saga name; unique per event type.
+	handle   func(ctx context.Context, event any) error
+}
+
+type eventBus struct {
+	mutex     sync.RWMutex
+	handlers  map[reflect.Type][]eventHandler
+	container *di.Container
+}
+
+func newEventBus(container *di.Container) *eventBus {
+	return &eventBus{
+		handlers:  make(map[reflect.Type][]eventHandler),
+		container: container,
+	}
+}
+
+func subscribe[TEvent any, THandler IEventHandler[TEvent]](r *eventBus, factoryFN any) {
+	// We use the DI to manage the handler's lifecycle
+	di.RegisterAsOn[THandler](r.container, factoryFN)
+
+	eventKey := normalizeType(reflect.TypeFor[TEvent]())
+	handlerType := reflect.TypeFor[THandler]()
+
+	r.add(eventKey, eventHandler{
+		identity: handlerType,
+		handle: func(ctx context.Context, event any) error {
+			typedEvent, err := coerce[TEvent](event, "event")
+			if err != nil {
+				return err
+			}
+
+			handlerInstance, err := di.TryResolveOn[THandler](r.container)
+			if err != nil {
+				return err
+			}
+
+			return handlerInstance.Handle(ctx, typedEvent)
+		},
+	})
+}
+
+// add appends the handler for the event type. Panics if the same handler is already registered for it.
+func (r *eventBus) add(eventKey reflect.Type, handler eventHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.handlers[eventKey] {
+		if existing.identity == handler.identity {
+			panic(fmt.Sprintf("cqrs: event handler %v already registered for type %v", handler.identity, eventKey))
+		}
+	}
+
+	r.handlers[eventKey] = append(r.handlers[eventKey], handler)
+}
+
+// publish delivers the event to every handler registered for its type.
+// Handler failures are aggregated with errors.Join; no handlers is not an error.
+func publish(r *eventBus, ctx context.Context, event any, strategy ...PublishStrategy) error {
+	eventKey, err := normalizedTypeKeyOfValue(event, "event")
+	if err != nil {
+		return err
+	}
+
+	r.mutex.RLock()
+	handlers := r.handlers[eventKey]
+	r.mutex.RUnlock()
+
+	selected := Sequential
+	if len(strategy) > 0 {
+		selected = strategy[0]
+	}
+
+	errorList := make([]error, len(handlers))
+
+	switch selected {
+	case Sequential:
+		for index, handler := range handlers {
+			if errorList[index] = handler.handle(ctx, event); errorList[index] != nil {
+				break
+			}
+		}
+	case Parallel:
+		var waitGroup sync.WaitGroup
+		for index, handler := range handlers {
+			waitGroup.Go(func() {
+				errorList[index] = handler.handle(ctx, event)
+			})
+		}
+		waitGroup.Wait()
+	case CollectAll:
+		for index, handler := range handlers {
+			errorList[index] = handler.handle(ctx, event)
+		}
+	default:
+		return fmt.Errorf("cqrs: unknown publish strategy %d", selected)
+	}
+
+	return errors.Join(errorList...)
+}
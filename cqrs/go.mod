@@ -2,4 +2,7 @@ module github.com/leandroluk/go/cqrs
 
 go 1.25
 
-require github.com/leandroluk/go/di v0.1.0
+require (
+	github.com/leandroluk/go/di v0.2.0
+	github.com/leandroluk/go/v v0.2.0
+)
@@ -0,0 +1,112 @@
+package cqrs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// IIdempotent is implemented by commands that carry an idempotency key (e.g. from an Idempotency-Key header).
+// Commands can also tag a field with `cqrs:"idempotency_key"`.
+type IIdempotent interface {
+	IdempotencyKey() string
+}
+
+const idempotencyTag = "idempotency_key"
+
+// idempotency records the results of commands that declare an idempotency key,
+// so duplicates within the window get the stored result instead of running again.
+type idempotency struct {
+	store     ICache
+	window    time.Duration
+	flights   flightGroup
+	tagFields sync.Map // reflect.Type -> int (field index, -1 if none)
+}
+
+func newIdempotency() *idempotency {
+	return &idempotency{
+		store:  NewMemoryCache(10_000),
+		window: 24 * time.Hour,
+	}
+}
+
+// WithIdempotency sets where command results are recorded and for how long duplicates get the stored result.
+// Defaults to NewMemoryCache(10_000) and 24 hours.
+func WithIdempotency(store ICache, window time.Duration) Option {
+	return func(mediator *Mediator) {
+		mediator.idempotency.store = store
+		mediator.idempotency.window = window
+	}
+}
+
+// run executes fn once per idempotency key: concurrent duplicates wait for the first call,
+// later ones get the recorded result. Failures are not recorded, so the command can be retried.
+func (i *idempotency) run(ctx context.Context, messageKey reflect.Type, message any, fn func(ctx context.Context) (any, error)) (any, error) {
+	if i == nil {
+		return fn(ctx)
+	}
+
+	idempotencyKey := i.keyOf(message)
+	if idempotencyKey == "" {
+		return fn(ctx)
+	}
+	key := fmt.Sprintf("cqrs:idempotency:%v:%s", messageKey, idempotencyKey)
+
+	if value, found, err := i.store.Get(ctx, key); err == nil && found {
+		return value, nil
+	}
+
+	result, err, _ := i.flights.do(ctx, key, func(ctx context.Context) (any, error) {
+		// A call that finished between the lookup above and joining the flight already recorded its result
+		if value, found, err := i.store.Get(ctx, key); err == nil && found {
+			return value, nil
+		}
+
+		result, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.store.Set(ctx, key, result, i.window); err != nil {
+			return nil, fmt.Errorf("cqrs: recording idempotency key %q: %w", idempotencyKey, err)
+		}
+		return result, nil
+	})
+	return result, err
+}
+
+// keyOf returns the idempotency key declared by the message through IIdempotent or the struct tag.
+func (i *idempotency) keyOf(message any) string {
+	if idempotent, ok := message.(IIdempotent); ok {
+		return idempotent.IdempotencyKey()
+	}
+
+	messageValue := reflect.ValueOf(message)
+	if messageValue.Kind() == reflect.Pointer {
+		if messageValue.IsNil() {
+			return ""
+		}
+		messageValue = messageValue.Elem()
+	}
+	if messageValue.Kind() != reflect.Struct {
+		return ""
+	}
+
+	fieldIndex, ok := i.tagFields.Load(messageValue.Type())
+	if !ok {
+		fieldIndex = -1
+		for index := range messageValue.NumField() {
+			if messageValue.Type().Field(index).Tag.Get("cqrs") == idempotencyTag {
+				fieldIndex = index
+				break
+			}
+		}
+		i.tagFields.Store(messageValue.Type(), fieldIndex)
+	}
+
+	if fieldIndex.(int) < 0 {
+		return ""
+	}
+	return fmt.Sprint(messageValue.Field(fieldIndex.(int)).Interface())
+}
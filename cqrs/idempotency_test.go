@@ -0,0 +1,130 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type PlaceOrderCommand struct {
+	RequestID string
+	Fail      bool
+}
+
+func (c PlaceOrderCommand) IdempotencyKey() string { return c.RequestID }
+
+type TransferCommand struct {
+	Reference string `cqrs:"idempotency_key"`
+}
+
+type CountingHandler[TCommand any] struct {
+	calls *atomic.Int32
+	delay time.Duration
+	fail  func(TCommand) bool
+}
+
+func (h *CountingHandler[TCommand]) Handle(ctx context.Context, c TCommand) (int32, error) {
+	time.Sleep(h.delay)
+	calls := h.calls.Add(1)
+	if h.fail != nil && h.fail(c) {
+		return 0, errors.New("failed")
+	}
+	return calls, nil
+}
+
+func TestCQRS_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	orderCalls, transferCalls := &atomic.Int32{}, &atomic.Int32{}
+
+	mediator := New(WithIdempotency(NewMemoryCache(100), time.Minute))
+	RegisterCommandHandlerOn[PlaceOrderCommand, int32, *CountingHandler[PlaceOrderCommand]](mediator, func() *CountingHandler[PlaceOrderCommand] {
+		return &CountingHandler[PlaceOrderCommand]{calls: orderCalls, delay: 10 * time.Millisecond, fail: func(c PlaceOrderCommand) bool { return c.Fail }}
+	})
+	RegisterCommandHandlerOn[*TransferCommand, int32, *CountingHandler[*TransferCommand]](mediator, func() *CountingHandler[*TransferCommand] {
+		return &CountingHandler[*TransferCommand]{calls: transferCalls}
+	})
+
+	t.Run("Should return the stored result for duplicates", func(t *testing.T) {
+		first, _ := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r1"})
+		second, _ := ExecuteCommandOn[int32](mediator, ctx, &PlaceOrderCommand{RequestID: "r1"})
+		other, _ := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r2"})
+		if first != 1 || second != 1 || other != 2 {
+			t.Errorf("Expected 1, 1, 2, got %d, %d, %d", first, second, other)
+		}
+	})
+
+	t.Run("Should read the key from the struct tag", func(t *testing.T) {
+		for range 3 {
+			_, _ = ExecuteCommandOn[int32](mediator, ctx, TransferCommand{Reference: "t1"})
+		}
+		_, _ = ExecuteCommandOn[int32](mediator, ctx, TransferCommand{})
+		_, _ = ExecuteCommandOn[int32](mediator, ctx, TransferCommand{})
+		if transferCalls.Load() != 3 {
+			t.Errorf("Expected 3 handler calls, got %d", transferCalls.Load())
+		}
+	})
+
+	t.Run("Should wait for concurrent duplicates", func(t *testing.T) {
+		orderCalls.Store(0)
+		var waitGroup sync.WaitGroup
+		for range 10 {
+			waitGroup.Go(func() {
+				if res, err := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r3"}); err != nil || res != 1 {
+					t.Errorf("Expected 1 without error, got %d and %v", res, err)
+				}
+			})
+		}
+		waitGroup.Wait()
+		if orderCalls.Load() != 1 {
+			t.Errorf("Expected 1 handler call, got %d", orderCalls.Load())
+		}
+	})
+
+	t.Run("Should not record failures", func(t *testing.T) {
+		orderCalls.Store(0)
+		for range 2 {
+			if _, err := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r4", Fail: true}); err == nil {
+				t.Error("Expected the failure to be returned")
+			}
+		}
+		if orderCalls.Load() != 2 {
+			t.Errorf("Expected 2 handler calls, got %d", orderCalls.Load())
+		}
+	})
+}
+
+func TestCQRS_IdempotencyWithFailingBehavior(t *testing.T) {
+	ctx := context.Background()
+	calls := &atomic.Int32{}
+
+	mediator := New(WithIdempotency(NewMemoryCache(100), time.Minute))
+	RegisterCommandHandlerOn[PlaceOrderCommand, int32, *CountingHandler[PlaceOrderCommand]](mediator, func() *CountingHandler[PlaceOrderCommand] {
+		return &CountingHandler[PlaceOrderCommand]{calls: calls}
+	})
+	commits := 0
+	mediator.UseCommandBehavior(func(ctx context.Context, message any, next HandlerFunc) (any, error) {
+		result, err := next(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		// The first commit fails after the handler succeeded, rolling the transaction back
+		if commits++; commits == 1 {
+			return nil, errors.New("commit failed")
+		}
+		return result, nil
+	})
+
+	if _, err := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r1"}); err == nil {
+		t.Fatal("Expected the failed commit to be reported")
+	}
+	result, err := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r1"})
+	if err != nil || result != 2 {
+		t.Errorf("Expected the retry to run the handler again, got %d, %v", result, err)
+	}
+	if duplicate, _ := ExecuteCommandOn[int32](mediator, ctx, PlaceOrderCommand{RequestID: "r1"}); duplicate != 2 || calls.Load() != 2 {
+		t.Errorf("Expected the committed result for the duplicate, got %d after %d calls", duplicate, calls.Load())
+	}
+}
@@ -35,6 +35,11 @@ func normalizedTypeKeyOfValue(value any, valueName string) (reflect.Type, error)
 func coerce[TExpected any](value any, valueName string) (TExpected, error) {
 	var zero TExpected
 
+	// Fast path: the value already has the expected type
+	if typed, ok := value.(TExpected); ok {
+		return typed, nil
+	}
+
 	if value == nil {
 		return zero, fmt.Errorf("cqrs: nil %s", valueName)
 	}
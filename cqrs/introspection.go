@@ -0,0 +1,49 @@
+package cqrs
+
+import (
+	"cmp"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// HandlerKind tells which kind of message a handler serves.
+type HandlerKind string
+
+const (
+	KindQuery       HandlerKind = "query"
+	KindCommand     HandlerKind = "command"
+	KindStreamQuery HandlerKind = "stream query"
+)
+
+// HandlerDescriptor describes a registered query, command or stream query handler.
+type HandlerDescriptor struct {
+	Kind        HandlerKind  // Which registry the handler belongs to.
+	MessageType reflect.Type // The message type as declared at registration (TQuery / TCommand).
+	ResultType  reflect.Type // The result type (TResult), or the item type (TItem) for stream queries.
+	HandlerType reflect.Type // The handler type resolved from the DI container (THandler).
+}
+
+// Handlers lists the handlers registered in the default mediator.
+func Handlers() []HandlerDescriptor {
+	return defaultMediator.Handlers()
+}
+
+// Handlers lists every registered query, command and stream query handler,
+// sorted by kind and message type name so the output is stable.
+func (m *Mediator) Handlers() []HandlerDescriptor {
+	var descriptors []HandlerDescriptor
+	for _, r := range []*registry{m.queries, m.commands, m.streams} {
+		r.mutex.RLock()
+		descriptors = slices.AppendSeq(descriptors, maps.Values(r.descriptors))
+		r.mutex.RUnlock()
+	}
+
+	slices.SortFunc(descriptors, func(a, b HandlerDescriptor) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.MessageType.String(), b.MessageType.String()),
+		)
+	})
+	return descriptors
+}
@@ -0,0 +1,77 @@
+package cqrs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/leandroluk/go/di"
+)
+
+// Mediator dispatches queries, commands and events to handlers resolved from its own DI container.
+// Each mediator has isolated registries, so tests and bounded contexts can run side by side.
+type Mediator struct {
+	container   *di.Container
+	queries     *registry
+	commands    *registry
+	streams     *registry
+	events      *eventBus
+	validation  *validation
+	cache       *queryCache
+	idempotency *idempotency
+	dispatcher  *dispatcher
+	sagaStore   ISagaStore
+	sagaMutex   sync.Mutex
+	sagas       []func(ctx context.Context) error
+}
+
+// Option configures a Mediator.
+type Option func(mediator *Mediator)
+
+// WithContainer sets the DI container used to register and resolve handlers.
+func WithContainer(container *di.Container) Option {
+	return func(mediator *Mediator) {
+		mediator.container = container
+	}
+}
+
+// WithSagaStore sets where saga instances are persisted. Defaults to NewMemorySagaStore().
+func WithSagaStore(store ISagaStore) Option {
+	return func(mediator *Mediator) {
+		mediator.sagaStore = store
+	}
+}
+
+var defaultMediator = New(WithContainer(di.Default()))
+
+// New creates an isolated mediator. Unless WithContainer is given, it gets a fresh DI container.
+func New(options ...Option) *Mediator {
+	mediator := &Mediator{
+		validation:  newValidation(),
+		cache:       newQueryCache(),
+		idempotency: newIdempotency(),
+		dispatcher:  newDispatcher(),
+		sagaStore:   NewMemorySagaStore(),
+	}
+	for _, option := range options {
+		option(mediator)
+	}
+	if mediator.container == nil {
+		mediator.container = di.New()
+	}
+	mediator.queries = newRegistry(string(KindQuery), mediator)
+	mediator.commands = newRegistry(string(KindCommand), mediator)
+	mediator.streams = newRegistry(string(KindStreamQuery), mediator)
+	mediator.events = newEventBus(mediator.container)
+	mediator.dispatcher.commands = mediator.commands
+	return mediator
+}
+
+// Default returns the mediator used by the package-level functions. It is wired to di.Default().
+func Default() *Mediator {
+	return defaultMediator
+}
+
+// Container returns the DI container the mediator resolves handlers from.
+func (m *Mediator) Container() *di.Container {
+	return m.container
+}
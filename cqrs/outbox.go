@@ -0,0 +1,87 @@
+package cqrs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Envelope wraps a command queued for asynchronous execution.
+type Envelope struct {
+	ID         string    // Unique identifier of the queued command.
+	Command    any       // The command to execute.
+	Attempts   int       // How many times execution has been attempted.
+	EnqueuedAt time.Time // When the command was sent.
+}
+
+// IOutbox stores commands waiting to be executed by the dispatcher workers.
+// The in-memory implementation is enough for a single process; a database can back it to survive restarts.
+type IOutbox interface {
+	// Enqueue stores the envelope, blocking while the outbox is full or until ctx is done.
+	// On Shutdown it also receives the envelopes whose retries were interrupted, with the same ID
+	// and their attempts so far, to replace the ones it handed out.
+	Enqueue(ctx context.Context, envelope Envelope) error
+	// Dequeue returns the next envelope, blocking until one is available or ctx is done.
+	Dequeue(ctx context.Context) (Envelope, error)
+	// Ack marks the envelope as finished, whether it succeeded or went to the dead letter.
+	Ack(ctx context.Context, envelope Envelope) error
+}
+
+// MemoryOutbox is a bounded in-memory IOutbox.
+type MemoryOutbox struct {
+	queue chan Envelope
+}
+
+var _ IOutbox = (*MemoryOutbox)(nil)
+
+// NewMemoryOutbox creates an in-memory outbox that holds up to capacity pending commands.
+func NewMemoryOutbox(capacity int) *MemoryOutbox {
+	return &MemoryOutbox{queue: make(chan Envelope, capacity)}
+}
+
+func (o *MemoryOutbox) Enqueue(ctx context.Context, envelope Envelope) error {
+	select {
+	case o.queue <- envelope:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (o *MemoryOutbox) Dequeue(ctx context.Context) (Envelope, error) {
+	select {
+	case envelope := <-o.queue:
+		return envelope, nil
+	case <-ctx.Done():
+		return Envelope{}, ctx.Err()
+	}
+}
+
+func (o *MemoryOutbox) Ack(ctx context.Context, envelope Envelope) error {
+	return nil
+}
+
+// Len returns the number of pending commands.
+func (o *MemoryOutbox) Len() int {
+	return len(o.queue)
+}
+
+// drain removes and returns the pending commands.
+func (o *MemoryOutbox) drain() []Envelope {
+	var envelopes []Envelope
+	for {
+		select {
+		case envelope := <-o.queue:
+			envelopes = append(envelopes, envelope)
+		default:
+			return envelopes
+		}
+	}
+}
+
+func newEnvelopeID() string {
+	buffer := make([]byte, 16)
+	_, _ = rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
@@ -0,0 +1,161 @@
+package cqrs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CachePolicy configures how the results of a query type are cached.
+type CachePolicy struct {
+	TTL           time.Duration // How long a result is kept; zero keeps it until evicted or invalidated.
+	InvalidatedBy []any         // Commands (e.g. UpdateUserCommand{}) whose success drops every cached result of the query type.
+}
+
+// queryCache caches query results per query type and drops them when configured commands succeed.
+// Every key includes the current generation of its query type, a random token stored in the ICache
+// itself: invalidation replaces the token, so every process sharing the cache stops reading the old
+// entries on its next lookup, and a lost token (evicted, or a fresh cache) only orphans them.
+type queryCache struct {
+	mutex       sync.RWMutex
+	cache       ICache
+	policies    map[reflect.Type]CachePolicy
+	invalidates map[reflect.Type][]reflect.Type
+	flights     flightGroup
+}
+
+func newQueryCache() *queryCache {
+	return &queryCache{
+		cache:       NewMemoryCache(1024),
+		policies:    make(map[reflect.Type]CachePolicy),
+		invalidates: make(map[reflect.Type][]reflect.Type),
+	}
+}
+
+// WithCache sets where cached query results are stored. Defaults to NewMemoryCache(1024).
+// Nothing is cached until a policy is set with CacheQuery.
+func WithCache(cache ICache) Option {
+	return func(mediator *Mediator) {
+		mediator.cache.cache = cache
+	}
+}
+
+// CacheQuery enables caching for results of TQuery on the default mediator.
+func CacheQuery[TQuery any](policy CachePolicy) {
+	CacheQueryOn[TQuery](defaultMediator, policy)
+}
+
+// CacheQueryOn enables caching for results of TQuery. Results are keyed by a hash of the JSON encoding
+// of the query, so only exported fields tell queries apart. Concurrent identical queries run the handler once.
+func CacheQueryOn[TQuery any](m *Mediator, policy CachePolicy) {
+	queryKey := normalizeType(reflect.TypeFor[TQuery]())
+
+	m.cache.mutex.Lock()
+	defer m.cache.mutex.Unlock()
+
+	m.cache.policies[queryKey] = policy
+	for _, command := range policy.InvalidatedBy {
+		commandKey := normalizeType(reflect.TypeOf(command))
+		m.cache.invalidates[commandKey] = append(m.cache.invalidates[commandKey], queryKey)
+	}
+}
+
+// InvalidateCache drops every cached result of TQuery on the default mediator.
+func InvalidateCache[TQuery any](ctx context.Context) error {
+	return InvalidateCacheOn[TQuery](defaultMediator, ctx)
+}
+
+// InvalidateCacheOn drops every cached result of TQuery, in every process sharing the ICache.
+func InvalidateCacheOn[TQuery any](m *Mediator, ctx context.Context) error {
+	return m.cache.invalidate(ctx, normalizeType(reflect.TypeFor[TQuery]()))
+}
+
+// invalidate starts a new generation for each query type.
+func (c *queryCache) invalidate(ctx context.Context, queryKeys ...reflect.Type) error {
+	var errorList []error
+	for _, queryKey := range queryKeys {
+		if err := c.cache.Set(ctx, generationKey(queryKey), newEnvelopeID(), 0); err != nil {
+			errorList = append(errorList, fmt.Errorf("cqrs: invalidate cached %v: %w", queryKey, err))
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// generation returns the current generation of the query type, starting one when the cache has none.
+func (c *queryCache) generation(ctx context.Context, queryKey reflect.Type) (string, error) {
+	value, found, err := c.cache.Get(ctx, generationKey(queryKey))
+	if err != nil {
+		return "", err
+	}
+	if generation, ok := value.(string); found && ok {
+		return generation, nil
+	}
+
+	generation := newEnvelopeID()
+	return generation, c.cache.Set(ctx, generationKey(queryKey), generation, 0)
+}
+
+// invalidateAfter invalidates the queries bound to the message type. It is called once the message went
+// through the whole pipeline, so a transaction behavior has committed before a new generation is filled.
+func (c *queryCache) invalidateAfter(ctx context.Context, messageKey reflect.Type) error {
+	c.mutex.RLock()
+	invalidates := c.invalidates[messageKey]
+	c.mutex.RUnlock()
+
+	if len(invalidates) == 0 {
+		return nil
+	}
+	return c.invalidate(ctx, invalidates...)
+}
+
+// run serves the message from the cache when its type has a policy. The cache is bypassed when it cannot be read.
+func (c *queryCache) run(ctx context.Context, messageKey reflect.Type, message any, fn func(ctx context.Context) (any, error)) (any, error) {
+	c.mutex.RLock()
+	policy, cached := c.policies[messageKey]
+	c.mutex.RUnlock()
+
+	if !cached {
+		return fn(ctx)
+	}
+
+	generation, err := c.generation(ctx, messageKey)
+	if err != nil {
+		return fn(ctx)
+	}
+	key, err := cacheKey(messageKey, generation, message)
+	if err != nil {
+		return fn(ctx)
+	}
+
+	if value, found, err := c.cache.Get(ctx, key); err == nil && found {
+		return value, nil
+	}
+
+	result, err, _ := c.flights.do(ctx, key, func(ctx context.Context) (any, error) {
+		result, err := fn(ctx)
+		if err == nil {
+			_ = c.cache.Set(ctx, key, result, policy.TTL)
+		}
+		return result, err
+	})
+	return result, err
+}
+
+// cacheKey builds a stable key from the query type, its cache generation and a hash of its JSON encoding.
+func cacheKey(messageKey reflect.Type, generation string, message any) (string, error) {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("cqrs:%v:%s:%x", messageKey, generation, sha256.Sum256(encoded)), nil
+}
+
+// generationKey is where the current generation of the query type is stored.
+func generationKey(queryKey reflect.Type) string {
+	return fmt.Sprintf("cqrs:%v:generation", queryKey)
+}
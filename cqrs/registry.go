@@ -10,74 +10,132 @@ import (
 )
 
 type registry struct {
-	mutex     sync.RWMutex
-	executors map[reflect.Type]func(ctx context.Context, message any) (any, error)
-	kindName  string
+	mutex       sync.RWMutex
+	executors   map[reflect.Type]func(ctx context.Context, message any) (any, error)
+	descriptors map[reflect.Type]HandlerDescriptor
+	behaviors   []Behavior
+	kindName    string
+	container   *di.Container
+	validation  *validation
+	cache       *queryCache
+	idempotency *idempotency
 }
 
-func newRegistry(kindName string) *registry {
-	return &registry{
-		executors: make(map[reflect.Type]func(context.Context, any) (any, error)),
-		kindName:  kindName,
+func newRegistry(kindName string, mediator *Mediator) *registry {
+	r := &registry{
+		executors:   make(map[reflect.Type]func(context.Context, any) (any, error)),
+		descriptors: make(map[reflect.Type]HandlerDescriptor),
+		kindName:    kindName,
+		container:   mediator.container,
+		validation:  mediator.validation,
+		cache:       mediator.cache,
 	}
+	if kindName == string(KindCommand) {
+		r.idempotency = mediator.idempotency
+	}
+	return r
+}
+
+func (r *registry) use(behaviors ...Behavior) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.behaviors = append(r.behaviors, behaviors...)
 }
 
-func register[TMessage any, TResult any, THandler any](r *registry, factoryFN any) {
+// messageHandler is the shape shared by IQueryHandler and ICommandHandler.
+type messageHandler[TMessage any, TResult any] interface {
+	Handle(ctx context.Context, message TMessage) (TResult, error)
+}
+
+func register[TMessage any, TResult any, THandler messageHandler[TMessage, TResult]](r *registry, factoryFN any) {
 	// We use the DI to manage the handler's lifecycle
-	di.RegisterAs[THandler](factoryFN)
+	di.RegisterAsOn[THandler](r.container, factoryFN)
 
 	messageKey := normalizeType(reflect.TypeFor[TMessage]())
 
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
-	if _, exists := r.executors[messageKey]; exists {
-		panic(fmt.Sprintf("cqrs: %s handler already registered for type %v", r.kindName, messageKey))
+	descriptor := HandlerDescriptor{
+		Kind:        HandlerKind(r.kindName),
+		MessageType: reflect.TypeFor[TMessage](),
+		ResultType:  reflect.TypeFor[TResult](),
+		HandlerType: reflect.TypeFor[THandler](),
 	}
 
-	r.executors[messageKey] = func(ctx context.Context, message any) (any, error) {
+	r.add(messageKey, descriptor, func(ctx context.Context, message any) (any, error) {
 		typedMessage, err := coerce[TMessage](message, r.kindName)
 		if err != nil {
 			return nil, err
 		}
 
-		handlerInstance := di.Resolve[THandler]()
+		typedMessage, err = validate(r.validation, messageKey, typedMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		return r.cache.run(ctx, messageKey, typedMessage, func(ctx context.Context) (any, error) {
+			handlerInstance, err := di.TryResolveOn[THandler](r.container)
+			if err != nil {
+				return nil, err
+			}
 
-		// Use reflection to call the Handle method
-		method := reflect.ValueOf(handlerInstance).MethodByName("Handle")
-		results := method.Call([]reflect.Value{
-			reflect.ValueOf(ctx),
-			reflect.ValueOf(typedMessage),
+			result, err := handlerInstance.Handle(ctx, typedMessage)
+			if err != nil {
+				return nil, err
+			}
+
+			return result, nil
 		})
+	})
+}
 
-		errResult := results[1].Interface()
-		if errResult != nil {
-			return nil, errResult.(error)
-		}
+// add stores the executor for the message type. Panics if one is already registered.
+func (r *registry) add(messageKey reflect.Type, descriptor HandlerDescriptor, executor func(ctx context.Context, message any) (any, error)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-		return results[0].Interface(), nil
+	if _, exists := r.executors[messageKey]; exists {
+		panic(fmt.Sprintf("cqrs: %s handler already registered for type %v", r.kindName, messageKey))
 	}
+
+	r.executors[messageKey] = executor
+	r.descriptors[messageKey] = descriptor
 }
 
 func execute[TResult any](r *registry, ctx context.Context, message any) (TResult, error) {
 	var zero TResult
-	messageKey, err := normalizedTypeKeyOfValue(message, r.kindName)
+
+	anyResult, err := dispatch(r, ctx, message)
 	if err != nil {
 		return zero, err
 	}
 
+	return coerce[TResult](anyResult, "result")
+}
+
+// dispatch runs the message through the behaviors and its handler, returning the untyped result.
+// Deduplication wraps the whole pipeline, so a result is only recorded once every behavior succeeded
+// (e.g. a transaction committed), and duplicates get it without running the pipeline again.
+func dispatch(r *registry, ctx context.Context, message any) (any, error) {
+	messageKey, err := normalizedTypeKeyOfValue(message, r.kindName)
+	if err != nil {
+		return nil, err
+	}
+
 	r.mutex.RLock()
 	executor, exists := r.executors[messageKey]
+	behaviors := r.behaviors
 	r.mutex.RUnlock()
 
 	if !exists {
-		return zero, fmt.Errorf("cqrs: no %s handler registered for type %v", r.kindName, messageKey)
+		return nil, fmt.Errorf("cqrs: no %s handler registered for type %v", r.kindName, messageKey)
 	}
 
-	anyResult, err := executor(ctx, message)
+	result, err := r.idempotency.run(ctx, messageKey, message, func(ctx context.Context) (any, error) {
+		return chain(behaviors, executor)(ctx, message)
+	})
 	if err != nil {
-		return zero, err
+		return nil, err
 	}
 
-	return coerce[TResult](anyResult, "result")
+	// A failed invalidation is reported along with the result, since the message itself succeeded
+	return result, r.cache.invalidateAfter(ctx, messageKey)
 }
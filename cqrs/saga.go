@@ -0,0 +1,371 @@
+package cqrs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// IIdentifiable is implemented by messages that carry a unique ID.
+// Sagas use it to handle each event only once, even when it is delivered again.
+type IIdentifiable interface {
+	MessageID() string
+}
+
+// SagaStep reacts to an event of a running saga: it updates the state and executes commands.
+// Returning an error fails the saga and runs the compensations recorded so far.
+type SagaStep[TState any, TEvent any] func(ctx context.Context, saga *SagaContext[TState], event TEvent) error
+
+// Saga is a process manager definition: which events start or advance it, and how they are correlated.
+type Saga[TState any] struct {
+	name      string
+	timeout   time.Duration
+	onTimeout func(ctx context.Context, saga *SagaContext[TState]) error
+	steps     []sagaStep[TState]
+	mutex     sync.Mutex
+	locks     map[string]*instanceLock // One per correlation ID being handled, so instances run independently.
+}
+
+type instanceLock struct {
+	sync.Mutex
+	references int
+}
+
+// sagaRunKey marks the context of the steps running for an instance.
+type sagaRunKey struct {
+	saga          any
+	correlationID string
+}
+
+// sagaRun collects the events published for an instance while one of its steps runs.
+type sagaRun[TState any] struct {
+	mutex   sync.Mutex
+	pending []sagaDelivery[TState]
+}
+
+// sagaDelivery is an event correlated to an instance, bound to the step that handles it.
+type sagaDelivery[TState any] struct {
+	starts bool
+	event  any
+	run    func(ctx context.Context, saga *SagaContext[TState]) error
+}
+
+type sagaStep[TState any] struct {
+	eventKey reflect.Type
+	starts   bool
+	// correlate coerces the event and returns its correlation ID and a step bound to the typed event.
+	correlate func(event any) (string, func(ctx context.Context, saga *SagaContext[TState]) error, error)
+}
+
+// SagaContext gives a saga step access to its state and to the mediator.
+type SagaContext[TState any] struct {
+	CorrelationID string
+	State         *TState
+	mediator      *Mediator
+	instance      *SagaInstance
+	completed     bool
+}
+
+// NewSaga creates a saga definition. The name identifies its instances in the store.
+func NewSaga[TState any](name string) *Saga[TState] {
+	return &Saga[TState]{name: name}
+}
+
+// Timeout fails running instances that are not completed within duration after they started.
+// The optional hook runs before the compensations, e.g. to publish a timeout event.
+func (s *Saga[TState]) Timeout(duration time.Duration, onTimeout ...func(ctx context.Context, saga *SagaContext[TState]) error) *Saga[TState] {
+	s.timeout = duration
+	if len(onTimeout) > 0 {
+		s.onTimeout = onTimeout[0]
+	}
+	return s
+}
+
+// SagaStartedBy makes TEvent start a new saga instance (or advance the running one with the same correlation ID).
+func SagaStartedBy[TEvent any, TState any](saga *Saga[TState], correlate func(event TEvent) string, step SagaStep[TState, TEvent]) {
+	addSagaStep(saga, true, correlate, step)
+}
+
+// SagaHandles makes TEvent advance a running saga instance. Events without a running instance are ignored.
+func SagaHandles[TEvent any, TState any](saga *Saga[TState], correlate func(event TEvent) string, step SagaStep[TState, TEvent]) {
+	addSagaStep(saga, false, correlate, step)
+}
+
+func addSagaStep[TEvent any, TState any](saga *Saga[TState], starts bool, correlate func(event TEvent) string, step SagaStep[TState, TEvent]) {
+	saga.steps = append(saga.steps, sagaStep[TState]{
+		eventKey: normalizeType(reflect.TypeFor[TEvent]()),
+		starts:   starts,
+		correlate: func(event any) (string, func(ctx context.Context, saga *SagaContext[TState]) error, error) {
+			typedEvent, err := coerce[TEvent](event, "event")
+			if err != nil {
+				return "", nil, err
+			}
+			return correlate(typedEvent), func(ctx context.Context, saga *SagaContext[TState]) error {
+				return step(ctx, saga, typedEvent)
+			}, nil
+		},
+	})
+}
+
+// RegisterSaga subscribes the saga to its events on the default mediator.
+func RegisterSaga[TState any](saga *Saga[TState]) {
+	RegisterSagaOn(defaultMediator, saga)
+}
+
+// RegisterSagaOn subscribes the saga to its events on the given mediator.
+func RegisterSagaOn[TState any](m *Mediator, saga *Saga[TState]) {
+	for _, step := range saga.steps {
+		m.events.add(step.eventKey, eventHandler{
+			identity: "saga:" + saga.name,
+			handle: func(ctx context.Context, event any) error {
+				return saga.handle(ctx, m, step, event)
+			},
+		})
+	}
+
+	m.sagaMutex.Lock()
+	defer m.sagaMutex.Unlock()
+	m.sagas = append(m.sagas, func(ctx context.Context) error {
+		return saga.expire(ctx, m)
+	})
+}
+
+// ExpireSagas times out every running saga instance of the default mediator whose deadline has passed.
+func ExpireSagas(ctx context.Context) error {
+	return defaultMediator.ExpireSagas(ctx)
+}
+
+// ExpireSagas times out every running saga instance whose deadline has passed.
+// Instances are also checked when they receive an event; call this periodically to catch idle ones.
+func (m *Mediator) ExpireSagas(ctx context.Context) error {
+	m.sagaMutex.Lock()
+	sagas := slices.Clone(m.sagas)
+	m.sagaMutex.Unlock()
+
+	var errorList []error
+	for _, expire := range sagas {
+		errorList = append(errorList, expire(ctx))
+	}
+	return errors.Join(errorList...)
+}
+
+// Execute dispatches the command through the mediator. Once it succeeds, compensation (if not nil)
+// is recorded and will be executed if the saga later fails or times out.
+func (sc *SagaContext[TState]) Execute(ctx context.Context, command any, compensation any) error {
+	if _, err := dispatch(sc.mediator.commands, ctx, command); err != nil {
+		return err
+	}
+	if compensation != nil {
+		sc.instance.Compensations = append(sc.instance.Compensations, compensation)
+	}
+	return nil
+}
+
+// Complete marks the saga as finished once the current step returns. Further events are ignored.
+func (sc *SagaContext[TState]) Complete() {
+	sc.completed = true
+}
+
+// Mediator returns the mediator running the saga, to publish events or execute queries.
+func (sc *SagaContext[TState]) Mediator() *Mediator {
+	return sc.mediator
+}
+
+func (s *Saga[TState]) handle(ctx context.Context, m *Mediator, step sagaStep[TState], event any) error {
+	correlationID, run, err := step.correlate(event)
+	if err != nil {
+		return err
+	}
+	delivery := sagaDelivery[TState]{starts: step.starts, event: event, run: run}
+
+	// An event published by a step of the same instance, through the context it was given,
+	// is handled once that step returns and its changes are saved
+	if current, ok := ctx.Value(sagaRunKey{s, correlationID}).(*sagaRun[TState]); ok {
+		current.mutex.Lock()
+		current.pending = append(current.pending, delivery)
+		current.mutex.Unlock()
+		return nil
+	}
+
+	return s.locked(ctx, m, correlationID, func(ctx context.Context) error {
+		return s.apply(ctx, m, correlationID, delivery)
+	})
+}
+
+// locked runs fn holding the lock of the instance, then the events its steps published for the same instance.
+// Errors of those events are reported to the caller, since their own Publish returned without handling them.
+func (s *Saga[TState]) locked(ctx context.Context, m *Mediator, correlationID string, fn func(ctx context.Context) error) error {
+	s.mutex.Lock()
+	if s.locks == nil {
+		s.locks = make(map[string]*instanceLock)
+	}
+	lock, ok := s.locks[correlationID]
+	if !ok {
+		lock = &instanceLock{}
+		s.locks[correlationID] = lock
+	}
+	lock.references++
+	s.mutex.Unlock()
+
+	lock.Lock()
+	defer func() {
+		lock.Unlock()
+		s.mutex.Lock()
+		if lock.references--; lock.references == 0 {
+			delete(s.locks, correlationID)
+		}
+		s.mutex.Unlock()
+	}()
+
+	current := &sagaRun[TState]{}
+	ctx = context.WithValue(ctx, sagaRunKey{s, correlationID}, current)
+
+	errorList := []error{fn(ctx)}
+	for {
+		current.mutex.Lock()
+		if len(current.pending) == 0 {
+			current.mutex.Unlock()
+			return errors.Join(errorList...)
+		}
+		next := current.pending[0]
+		current.pending = current.pending[1:]
+		current.mutex.Unlock()
+
+		errorList = append(errorList, s.apply(ctx, m, correlationID, next))
+	}
+}
+
+// apply runs the step of the delivery on the instance as stored, and saves the result. Called holding the instance lock.
+func (s *Saga[TState]) apply(ctx context.Context, m *Mediator, correlationID string, delivery sagaDelivery[TState]) error {
+	instance, found, err := m.sagaStore.Load(ctx, s.name, correlationID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if !delivery.starts {
+			return nil
+		}
+		instance = &SagaInstance{Saga: s.name, CorrelationID: correlationID, Status: SagaRunning}
+		if s.timeout > 0 {
+			instance.Deadline = time.Now().Add(s.timeout)
+		}
+	}
+	if instance.Status != SagaRunning {
+		return nil
+	}
+	if !instance.Deadline.IsZero() && instance.Deadline.Before(time.Now()) {
+		return s.timeOut(ctx, m, instance)
+	}
+
+	messageID := ""
+	if identifiable, ok := delivery.event.(IIdentifiable); ok {
+		messageID = identifiable.MessageID()
+	}
+	if messageID != "" && slices.Contains(instance.Processed, messageID) {
+		return nil
+	}
+
+	sagaContext, err := s.newContext(m, instance)
+	if err != nil {
+		return err
+	}
+
+	if err := delivery.run(ctx, sagaContext); err != nil {
+		stepErr := fmt.Errorf("cqrs: saga %s (%s) failed: %w", s.name, correlationID, err)
+		return errors.Join(stepErr, s.fail(ctx, m, sagaContext, SagaCompensated))
+	}
+
+	instance.State = *sagaContext.State
+	if messageID != "" {
+		instance.Processed = append(instance.Processed, messageID)
+	}
+	if sagaContext.completed {
+		instance.Status = SagaCompleted
+	}
+	return m.sagaStore.Save(ctx, instance)
+}
+
+func (s *Saga[TState]) expire(ctx context.Context, m *Mediator) error {
+	instances, err := m.sagaStore.Expired(ctx, s.name, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errorList []error
+	for _, expired := range instances {
+		errorList = append(errorList, s.locked(ctx, m, expired.CorrelationID, func(ctx context.Context) error {
+			// Reloaded under the lock, in case an event finished the instance in the meantime
+			instance, found, err := m.sagaStore.Load(ctx, s.name, expired.CorrelationID)
+			if err != nil || !found || instance.Status != SagaRunning {
+				return err
+			}
+			return s.timeOut(ctx, m, instance)
+		}))
+	}
+	return errors.Join(errorList...)
+}
+
+func (s *Saga[TState]) timeOut(ctx context.Context, m *Mediator, instance *SagaInstance) error {
+	sagaContext, err := s.newContext(m, instance)
+	if err != nil {
+		return err
+	}
+
+	var hookErr error
+	if s.onTimeout != nil {
+		hookErr = s.onTimeout(ctx, sagaContext)
+	}
+	return errors.Join(hookErr, s.fail(ctx, m, sagaContext, SagaTimedOut))
+}
+
+// fail runs the recorded compensations in reverse order and saves the instance with the final status.
+func (s *Saga[TState]) fail(ctx context.Context, m *Mediator, sagaContext *SagaContext[TState], status SagaStatus) error {
+	instance := sagaContext.instance
+
+	var errorList []error
+	for _, compensation := range slices.Backward(instance.Compensations) {
+		if _, err := dispatch(m.commands, ctx, compensation); err != nil {
+			errorList = append(errorList, fmt.Errorf("cqrs: saga %s (%s) compensation %T failed: %w", s.name, instance.CorrelationID, compensation, err))
+		}
+	}
+
+	instance.State = *sagaContext.State
+	instance.Status = status
+	instance.Compensations = nil
+	errorList = append(errorList, m.sagaStore.Save(ctx, instance))
+	return errors.Join(errorList...)
+}
+
+// newContext restores the typed state of the instance. States loaded as JSON from a store are decoded.
+func (s *Saga[TState]) newContext(m *Mediator, instance *SagaInstance) (*SagaContext[TState], error) {
+	state := new(TState)
+	switch stored := instance.State.(type) {
+	case nil:
+	case TState:
+		*state = stored
+	case *TState:
+		*state = *stored
+	case json.RawMessage:
+		if err := json.Unmarshal(stored, state); err != nil {
+			return nil, fmt.Errorf("cqrs: saga %s (%s) state: %w", s.name, instance.CorrelationID, err)
+		}
+	case []byte:
+		if err := json.Unmarshal(stored, state); err != nil {
+			return nil, fmt.Errorf("cqrs: saga %s (%s) state: %w", s.name, instance.CorrelationID, err)
+		}
+	default:
+		return nil, fmt.Errorf("cqrs: saga %s (%s) state: expected %v, got %T", s.name, instance.CorrelationID, reflect.TypeFor[TState](), stored)
+	}
+
+	return &SagaContext[TState]{
+		CorrelationID: instance.CorrelationID,
+		State:         state,
+		mediator:      m,
+		instance:      instance,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package cqrs
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SagaStatus is the lifecycle stage of a saga instance.
+type SagaStatus string
+
+const (
+	SagaRunning     SagaStatus = "running"
+	SagaCompleted   SagaStatus = "completed"
+	SagaCompensated SagaStatus = "compensated"
+	SagaTimedOut    SagaStatus = "timed_out"
+)
+
+// SagaInstance is the persisted state of one saga run, identified by its saga name and correlation ID.
+type SagaInstance struct {
+	Saga          string     // Name of the saga definition.
+	CorrelationID string     // Identifies the run across events (e.g. the order ID).
+	Status        SagaStatus // Lifecycle stage; finished instances ignore further events.
+	State         any        // The saga state (TState).
+	Compensations []any      // Commands to run, in reverse order, if the saga fails.
+	Processed     []string   // Message IDs already handled, for idempotency.
+	Deadline      time.Time  // When the saga times out; zero if it has no timeout.
+}
+
+// ISagaStore persists saga instances between events.
+// Implementations backed by a database must preserve the concrete types of State and Compensations.
+type ISagaStore interface {
+	// Load returns the instance, or false if it does not exist.
+	Load(ctx context.Context, saga string, correlationID string) (*SagaInstance, bool, error)
+	// Save creates or replaces the instance.
+	Save(ctx context.Context, instance *SagaInstance) error
+	// Expired returns the running instances of the saga whose deadline is before now.
+	Expired(ctx context.Context, saga string, now time.Time) ([]*SagaInstance, error)
+}
+
+// MemorySagaStore is an in-memory ISagaStore.
+type MemorySagaStore struct {
+	mutex     sync.RWMutex
+	instances map[[2]string]*SagaInstance
+}
+
+var _ ISagaStore = (*MemorySagaStore)(nil)
+
+// NewMemorySagaStore creates an empty in-memory saga store.
+func NewMemorySagaStore() *MemorySagaStore {
+	return &MemorySagaStore{instances: make(map[[2]string]*SagaInstance)}
+}
+
+func (s *MemorySagaStore) Load(ctx context.Context, saga string, correlationID string) (*SagaInstance, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	instance, ok := s.instances[[2]string{saga, correlationID}]
+	if !ok {
+		return nil, false, nil
+	}
+	return cloneSagaInstance(instance), true, nil
+}
+
+func (s *MemorySagaStore) Save(ctx context.Context, instance *SagaInstance) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.instances[[2]string{instance.Saga, instance.CorrelationID}] = cloneSagaInstance(instance)
+	return nil
+}
+
+func (s *MemorySagaStore) Expired(ctx context.Context, saga string, now time.Time) ([]*SagaInstance, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var expired []*SagaInstance
+	for _, instance := range s.instances {
+		if instance.Saga == saga && instance.Status == SagaRunning && !instance.Deadline.IsZero() && instance.Deadline.Before(now) {
+			expired = append(expired, cloneSagaInstance(instance))
+		}
+	}
+	return expired, nil
+}
+
+func cloneSagaInstance(instance *SagaInstance) *SagaInstance {
+	clone := *instance
+	clone.Compensations = slices.Clone(instance.Compensations)
+	clone.Processed = slices.Clone(instance.Processed)
+	return &clone
+}
@@ -0,0 +1,264 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type OrderPlaced struct {
+	EventID string
+	OrderID string
+}
+
+func (e OrderPlaced) MessageID() string { return e.EventID }
+
+type StockReserved struct{ OrderID string }
+
+type ReserveStock struct{ OrderID string }
+type ReleaseStock struct{ OrderID string }
+type ShipOrder struct{ OrderID string }
+
+type OrderSagaState struct {
+	Reservations int
+	Shipped      bool
+}
+
+type commandLog struct {
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *commandLog) add(entry string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func (l *commandLog) list() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return slices.Clone(l.entries)
+}
+
+type LoggingCommandHandler[TCommand any] struct {
+	log  *commandLog
+	name string
+	fail func(TCommand) bool
+}
+
+func (h *LoggingCommandHandler[TCommand]) Handle(ctx context.Context, command TCommand) (bool, error) {
+	h.log.add(h.name)
+	if h.fail != nil && h.fail(command) {
+		return false, errors.New(h.name + " failed")
+	}
+	return true, nil
+}
+
+func newOrderSagaMediator(t *testing.T, timeout time.Duration) (*Mediator, *commandLog, *MemorySagaStore) {
+	t.Helper()
+	log := &commandLog{}
+	store := NewMemorySagaStore()
+	mediator := New(WithSagaStore(store))
+
+	RegisterCommandHandlerOn[ReserveStock, bool, *LoggingCommandHandler[ReserveStock]](mediator, func() *LoggingCommandHandler[ReserveStock] {
+		return &LoggingCommandHandler[ReserveStock]{log: log, name: "reserve"}
+	})
+	RegisterCommandHandlerOn[ReleaseStock, bool, *LoggingCommandHandler[ReleaseStock]](mediator, func() *LoggingCommandHandler[ReleaseStock] {
+		return &LoggingCommandHandler[ReleaseStock]{log: log, name: "release"}
+	})
+	RegisterCommandHandlerOn[ShipOrder, bool, *LoggingCommandHandler[ShipOrder]](mediator, func() *LoggingCommandHandler[ShipOrder] {
+		return &LoggingCommandHandler[ShipOrder]{log: log, name: "ship", fail: func(c ShipOrder) bool { return c.OrderID == "bad" }}
+	})
+
+	saga := NewSaga[OrderSagaState]("order").Timeout(timeout)
+	SagaStartedBy(saga, func(e OrderPlaced) string { return e.OrderID },
+		func(ctx context.Context, saga *SagaContext[OrderSagaState], e OrderPlaced) error {
+			saga.State.Reservations++
+			return saga.Execute(ctx, ReserveStock{OrderID: e.OrderID}, ReleaseStock{OrderID: e.OrderID})
+		})
+	SagaHandles(saga, func(e StockReserved) string { return e.OrderID },
+		func(ctx context.Context, saga *SagaContext[OrderSagaState], e StockReserved) error {
+			if err := saga.Execute(ctx, ShipOrder{OrderID: e.OrderID}, nil); err != nil {
+				return err
+			}
+			saga.State.Shipped = true
+			saga.Complete()
+			return nil
+		})
+	RegisterSagaOn(mediator, saga)
+
+	return mediator, log, store
+}
+
+func TestCQRS_Saga(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should run steps and complete", func(t *testing.T) {
+		mediator, log, store := newOrderSagaMediator(t, time.Minute)
+
+		if err := mediator.Publish(ctx, OrderPlaced{EventID: "e1", OrderID: "1"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		// Same message ID delivered again is ignored
+		if err := mediator.Publish(ctx, &OrderPlaced{EventID: "e1", OrderID: "1"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := mediator.Publish(ctx, StockReserved{OrderID: "1"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		// Finished sagas ignore late events
+		if err := mediator.Publish(ctx, OrderPlaced{EventID: "e2", OrderID: "1"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if entries := log.list(); !slices.Equal(entries, []string{"reserve", "ship"}) {
+			t.Errorf("Expected [reserve ship], got %v", entries)
+		}
+		instance, _, _ := store.Load(ctx, "order", "1")
+		state := instance.State.(OrderSagaState)
+		if instance.Status != SagaCompleted || state.Reservations != 1 || !state.Shipped {
+			t.Errorf("Unexpected instance %+v", instance)
+		}
+	})
+
+	t.Run("Should ignore events without a running instance", func(t *testing.T) {
+		mediator, log, _ := newOrderSagaMediator(t, time.Minute)
+		if err := mediator.Publish(ctx, StockReserved{OrderID: "2"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if entries := log.list(); len(entries) != 0 {
+			t.Errorf("Expected no commands, got %v", entries)
+		}
+	})
+
+	t.Run("Should compensate on failure", func(t *testing.T) {
+		mediator, log, store := newOrderSagaMediator(t, time.Minute)
+		_ = mediator.Publish(ctx, OrderPlaced{EventID: "e1", OrderID: "bad"})
+
+		err := mediator.Publish(ctx, StockReserved{OrderID: "bad"})
+		if err == nil {
+			t.Fatal("Expected the saga failure to be reported")
+		}
+		if entries := log.list(); !slices.Equal(entries, []string{"reserve", "ship", "release"}) {
+			t.Errorf("Expected [reserve ship release], got %v", entries)
+		}
+		if instance, _, _ := store.Load(ctx, "order", "bad"); instance.Status != SagaCompensated {
+			t.Errorf("Expected compensated status, got %s", instance.Status)
+		}
+	})
+
+	t.Run("Should compensate on timeout", func(t *testing.T) {
+		mediator, log, store := newOrderSagaMediator(t, time.Millisecond)
+		_ = mediator.Publish(ctx, OrderPlaced{EventID: "e1", OrderID: "3"})
+		time.Sleep(5 * time.Millisecond)
+
+		if err := mediator.ExpireSagas(ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if entries := log.list(); !slices.Equal(entries, []string{"reserve", "release"}) {
+			t.Errorf("Expected [reserve release], got %v", entries)
+		}
+		if instance, _, _ := store.Load(ctx, "order", "3"); instance.Status != SagaTimedOut {
+			t.Errorf("Expected timed out status, got %s", instance.Status)
+		}
+	})
+}
+
+type ChargePayment struct{ OrderID string }
+type PaymentCharged struct{ OrderID string }
+
+type ChargePaymentHandler struct {
+	mediator *Mediator
+}
+
+func (h *ChargePaymentHandler) Handle(ctx context.Context, command ChargePayment) (bool, error) {
+	return true, h.mediator.Publish(ctx, PaymentCharged{OrderID: command.OrderID})
+}
+
+func TestCQRS_SagaEventFromCommandHandler(t *testing.T) {
+	ctx := context.Background()
+	log := &commandLog{}
+	store := NewMemorySagaStore()
+	mediator := New(WithSagaStore(store))
+
+	RegisterCommandHandlerOn[ChargePayment, bool, *ChargePaymentHandler](mediator, func() *ChargePaymentHandler {
+		return &ChargePaymentHandler{mediator: mediator}
+	})
+	RegisterCommandHandlerOn[ShipOrder, bool, *LoggingCommandHandler[ShipOrder]](mediator, func() *LoggingCommandHandler[ShipOrder] {
+		return &LoggingCommandHandler[ShipOrder]{log: log, name: "ship"}
+	})
+
+	saga := NewSaga[OrderSagaState]("payment")
+	SagaStartedBy(saga, func(e OrderPlaced) string { return e.OrderID },
+		func(ctx context.Context, saga *SagaContext[OrderSagaState], e OrderPlaced) error {
+			saga.State.Reservations++
+			return saga.Execute(ctx, ChargePayment{OrderID: e.OrderID}, nil)
+		})
+	SagaHandles(saga, func(e PaymentCharged) string { return e.OrderID },
+		func(ctx context.Context, saga *SagaContext[OrderSagaState], e PaymentCharged) error {
+			if err := saga.Execute(ctx, ShipOrder{OrderID: e.OrderID}, nil); err != nil {
+				return err
+			}
+			saga.State.Shipped = true
+			saga.Complete()
+			return nil
+		})
+	RegisterSagaOn(mediator, saga)
+
+	done := make(chan error, 1)
+	go func() { done <- mediator.Publish(ctx, OrderPlaced{EventID: "e1", OrderID: "1"}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish deadlocked")
+	}
+
+	if entries := log.list(); !slices.Equal(entries, []string{"ship"}) {
+		t.Errorf("Expected [ship], got %v", entries)
+	}
+	// The state saved by the nested event is kept, on top of the state of the first step
+	instance, _, _ := store.Load(ctx, "payment", "1")
+	state := instance.State.(OrderSagaState)
+	if instance.Status != SagaCompleted || state.Reservations != 1 || !state.Shipped {
+		t.Errorf("Unexpected instance %+v", instance)
+	}
+}
+
+func TestCQRS_SagaInstancesRunInParallel(t *testing.T) {
+	ctx := context.Background()
+	mediator := New()
+	started := make(chan string, 2)
+	release := make(chan struct{})
+
+	saga := NewSaga[OrderSagaState]("parallel")
+	SagaStartedBy(saga, func(e OrderPlaced) string { return e.OrderID },
+		func(ctx context.Context, saga *SagaContext[OrderSagaState], e OrderPlaced) error {
+			started <- e.OrderID
+			<-release
+			return nil
+		})
+	RegisterSagaOn(mediator, saga)
+
+	var waitGroup sync.WaitGroup
+	for _, orderID := range []string{"1", "2"} {
+		waitGroup.Go(func() {
+			_ = mediator.Publish(ctx, OrderPlaced{EventID: orderID, OrderID: orderID})
+		})
+	}
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("Expected instances with different correlation IDs to run in parallel")
+		}
+	}
+	close(release)
+	waitGroup.Wait()
+}
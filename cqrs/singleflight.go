@@ -0,0 +1,57 @@
+package cqrs
+
+import (
+	"context"
+	"sync"
+)
+
+// flight is a call in progress for a key.
+type flight struct {
+	done     chan struct{}
+	result   any
+	err      error
+	panicked any // The value fn panicked with, re-raised in every caller.
+}
+
+// flightGroup collapses concurrent calls with the same key into one execution.
+type flightGroup struct {
+	mutex   sync.Mutex
+	flights map[string]*flight
+}
+
+// do executes fn once for all concurrent callers of key. shared reports whether the result came from another caller.
+// fn runs on a context that is not cancelled with the caller that started it, so one caller giving up does not
+// fail the others; each caller stops waiting when its own ctx is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (result any, err error, shared bool) {
+	g.mutex.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	current, shared := g.flights[key]
+	if !shared {
+		current = &flight{done: make(chan struct{})}
+		g.flights[key] = current
+
+		go func() {
+			defer func() {
+				current.panicked = recover()
+				g.mutex.Lock()
+				delete(g.flights, key)
+				g.mutex.Unlock()
+				close(current.done)
+			}()
+			current.result, current.err = fn(context.WithoutCancel(ctx))
+		}()
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-current.done:
+		if current.panicked != nil {
+			panic(current.panicked)
+		}
+		return current.result, current.err, shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), shared
+	}
+}
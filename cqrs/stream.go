@@ -0,0 +1,84 @@
+package cqrs
+
+import (
+	"context"
+	"iter"
+	"reflect"
+
+	"github.com/leandroluk/go/di"
+)
+
+func registerStream[TQuery any, TItem any, THandler IStreamQueryHandler[TQuery, TItem]](r *registry, factoryFN any) {
+	// We use the DI to manage the handler's lifecycle
+	di.RegisterAsOn[THandler](r.container, factoryFN)
+
+	queryKey := normalizeType(reflect.TypeFor[TQuery]())
+
+	descriptor := HandlerDescriptor{
+		Kind:        HandlerKind(r.kindName),
+		MessageType: reflect.TypeFor[TQuery](),
+		ResultType:  reflect.TypeFor[TItem](),
+		HandlerType: reflect.TypeFor[THandler](),
+	}
+
+	r.add(queryKey, descriptor, func(ctx context.Context, message any) (any, error) {
+		typedQuery, err := coerce[TQuery](message, r.kindName)
+		if err != nil {
+			return nil, err
+		}
+
+		typedQuery, err = validate(r.validation, queryKey, typedQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		handlerInstance, err := di.TryResolveOn[THandler](r.container)
+		if err != nil {
+			return nil, err
+		}
+		items := handlerInstance.Handle(ctx, typedQuery)
+
+		return iter.Seq2[any, error](func(yield func(any, error) bool) {
+			for item, err := range items {
+				if !yield(item, err) {
+					return
+				}
+			}
+		}), nil
+	})
+}
+
+// executeStream resolves the handler lazily, when the sequence is ranged over.
+// Dispatch errors are yielded as a single (zero, err) pair, and the context is checked before every item.
+func executeStream[TItem any](r *registry, ctx context.Context, query any) iter.Seq2[TItem, error] {
+	return func(yield func(TItem, error) bool) {
+		var zero TItem
+
+		if err := ctx.Err(); err != nil {
+			yield(zero, err)
+			return
+		}
+
+		items, err := execute[iter.Seq2[any, error]](r, ctx, query)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for item, err := range items {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				yield(zero, ctxErr)
+				return
+			}
+			if err != nil {
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+			if !yield(coerce[TItem](item, "item")) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cqrs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/leandroluk/go/v"
+)
+
+// validation holds which message types are checked against the v schema registry before reaching their handlers.
+type validation struct {
+	mutex   sync.RWMutex
+	all     bool
+	options []v.Option
+	byType  map[reflect.Type][]v.Option
+}
+
+func newValidation() *validation {
+	return &validation{
+		byType: make(map[reflect.Type][]v.Option),
+	}
+}
+
+// WithValidation validates every query and command that has a schema registered in v before it reaches its handler.
+// Messages without a schema go through untouched.
+func WithValidation(options ...v.Option) Option {
+	return func(mediator *Mediator) {
+		mediator.validation.all = true
+		mediator.validation.options = options
+	}
+}
+
+// EnableValidation validates messages of type TMessage against their v schema before they reach their handler.
+// Unlike WithValidation, a missing schema is reported as an error wrapping v.ErrNoSchema.
+func EnableValidation[TMessage any](options ...v.Option) {
+	EnableValidationOn[TMessage](defaultMediator, options...)
+}
+
+func EnableValidationOn[TMessage any](m *Mediator, options ...v.Option) {
+	messageKey := normalizeType(reflect.TypeFor[TMessage]())
+
+	m.validation.mutex.Lock()
+	defer m.validation.mutex.Unlock()
+	m.validation.byType[messageKey] = options
+}
+
+// validate runs the schema registered for the message type and returns the validated (defaults applied) message.
+func validate[TMessage any](s *validation, messageKey reflect.Type, message TMessage) (TMessage, error) {
+	s.mutex.RLock()
+	options, required := s.byType[messageKey]
+	if !required && s.all {
+		options = s.options
+	}
+	enabled := required || s.all
+	s.mutex.RUnlock()
+
+	if !enabled {
+		return message, nil
+	}
+
+	var zero TMessage
+	var input any = message
+	if messageValue := reflect.ValueOf(message); messageValue.Kind() == reflect.Pointer {
+		if messageValue.IsNil() {
+			return zero, fmt.Errorf("cqrs: nil %v pointer", messageKey)
+		}
+		input = messageValue.Elem().Interface()
+	}
+
+	output, err := v.ValidateType(messageKey, input, options...)
+	if err != nil {
+		if !required && errors.Is(err, v.ErrNoSchema) {
+			return message, nil
+		}
+		return zero, err
+	}
+
+	return coerce[TMessage](output, "validated message")
+}
@@ -0,0 +1,73 @@
+package di
+
+import "sync"
+
+// Container holds a set of providers and the singletons built from them.
+// The package-level functions operate on a default container; use New to get an isolated one.
+type Container struct {
+	mutex     sync.RWMutex
+	providers map[providerKey][]*Provider
+	parent    *Container
+	scope     *scopeState // Set on containers created by NewScope.
+	built     []*Provider // Singletons in the order they were built, for Start and Stop.
+}
+
+var defaultContainer = New()
+
+// New creates an empty, isolated container.
+func New() *Container {
+	return &Container{
+		providers: make(map[providerKey][]*Provider),
+	}
+}
+
+// Default returns the container used by the package-level functions.
+func Default() *Container {
+	return defaultContainer
+}
+
+// Child creates a container that falls back to c for every provider it does not register itself,
+// e.g. one per test, to override some providers (see OverrideOn) without touching c.
+func (c *Container) Child() *Container {
+	return &Container{
+		providers: make(map[providerKey][]*Provider),
+		parent:    c,
+	}
+}
+
+// Register adds a transient provider to the container.
+func (c *Container) Register(factoryFN any) {
+	c.registerProvider(factoryFN, transient, nil, "")
+}
+
+// Singleton adds a singleton provider to the container.
+func (c *Container) Singleton(factoryFN any) {
+	c.registerProvider(factoryFN, singleton, nil, "")
+}
+
+// Scoped adds a scoped provider to the container.
+func (c *Container) Scoped(factoryFN any) {
+	c.registerProvider(factoryFN, scoped, nil, "")
+}
+
+// Reset clears all providers registered in the container.
+func (c *Container) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.providers = make(map[providerKey][]*Provider)
+	c.built = nil
+}
+
+// lookup returns the providers for the key, searching the container and then its parents.
+func (c *Container) lookup(key providerKey) []*Provider {
+	for container := c; container != nil; container = container.parent {
+		container.mutex.RLock()
+		providers := container.providers[key]
+		container.mutex.RUnlock()
+
+		if len(providers) > 0 {
+			return providers
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// decorator wraps the instances of a provider. Its first parameter receives the inner instance.
+type decorator struct {
+	function   reflect.Value
+	parameters []parameter // The dependencies after the inner instance.
+}
+
+// Decorate wraps the providers of T registered in the default container with decoratorFN.
+func Decorate[T any](decoratorFN any) {
+	DecorateOn[T](defaultContainer, decoratorFN)
+}
+
+// DecorateOn wraps every provider of T visible from the container with decoratorFN, a function
+// taking the inner T (plus any dependencies) and returning T or (T, error), e.g. to add caching or logging.
+// Decorators stack in registration order, the last one outermost, and run once per instance built,
+// so a decorated singleton is decorated once. Transient and scoped instances built before the call
+// are not decorated.
+// Providers of a parent container are left untouched: like OverrideOn, the container gets decorated
+// copies of them, and singletons depending on T are rebuilt in the container to receive the decorated T.
+// Panics if T has no provider, or if one of its singletons in the container is already built,
+// since the decorator could never apply to it.
+func DecorateOn[T any](c *Container, decoratorFN any) {
+	targetType := reflect.TypeFor[T]()
+
+	if decoratorFN == nil {
+		panic("di: nil decorator function provided")
+	}
+	decoratorValue := reflect.ValueOf(decoratorFN)
+	decoratorType := decoratorValue.Type()
+
+	if decoratorType.Kind() != reflect.Func || decoratorType.NumIn() == 0 || decoratorType.In(0) != targetType {
+		panic(fmt.Sprintf("di: decorator must be a function taking %v as its first parameter", targetType))
+	}
+	switch {
+	case decoratorType.NumOut() == 1 && decoratorType.Out(0) == targetType:
+	case decoratorType.NumOut() == 2 && decoratorType.Out(0) == targetType && decoratorType.Out(1) == errorType:
+	default:
+		panic(fmt.Sprintf("di: decorator must return %v, or %v and an error", targetType, targetType))
+	}
+
+	key := providerKey{Type: targetType}
+	providers := c.lookup(key)
+	if len(providers) == 0 {
+		panic(fmt.Sprintf("di: no provider registered for %v to decorate", targetType))
+	}
+
+	// Skip the inner instance, the remaining parameters are resolved like a factory's
+	dependencyTypes := make([]reflect.Type, decoratorType.NumIn()-1)
+	for index := range dependencyTypes {
+		dependencyTypes[index] = decoratorType.In(index + 1)
+	}
+	decoratorInstance := decorator{
+		function:   decoratorValue,
+		parameters: parseParameters(reflect.FuncOf(dependencyTypes, nil, false)),
+	}
+
+	if providers[0].container == c {
+		for _, providerInstance := range providers {
+			if providerInstance.isBuilt() {
+				panic(fmt.Sprintf("di: cannot decorate %v, its singleton is already built", targetType))
+			}
+		}
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for _, providerInstance := range providers {
+			providerInstance.decorators = append(providerInstance.decorators, decoratorInstance)
+		}
+		return
+	}
+
+	replacements := c.dependentSingletons(key)
+	decorated := make([]*Provider, len(providers))
+	for index, providerInstance := range providers {
+		decorated[index] = c.copyProvider(providerInstance)
+		decorated[index].decorators = append(decorated[index].decorators, decoratorInstance)
+	}
+	replacements[key] = decorated
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for replacedKey, replacedProviders := range replacements {
+		c.providers[replacedKey] = replacedProviders
+	}
+}
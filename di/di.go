@@ -4,48 +4,137 @@ import "reflect"
 
 // Register adds a transient provider (a new instance is created every time it's resolved).
 func Register(factoryFN any) {
-	registerProvider(factoryFN, false, nil)
+	defaultContainer.Register(factoryFN)
 }
 
 // RegisterAs adds a transient provider bound to a specific interface or type T.
 func RegisterAs[T any](factoryFN any) {
-	registerProvider(factoryFN, false, reflect.TypeFor[T]())
+	RegisterAsOn[T](defaultContainer, factoryFN)
+}
+
+// RegisterAsOn adds a transient provider bound to T in the given container.
+func RegisterAsOn[T any](c *Container, factoryFN any) {
+	c.registerProvider(factoryFN, transient, reflect.TypeFor[T](), "")
 }
 
 // Singleton adds a provider that caches its instance after the first resolution.
 func Singleton(factoryFN any) {
-	registerProvider(factoryFN, true, nil)
+	defaultContainer.Singleton(factoryFN)
 }
 
 // SingletonAs adds a singleton provider bound to a specific interface or type T.
 func SingletonAs[T any](factoryFN any) {
-	registerProvider(factoryFN, true, reflect.TypeFor[T]())
+	SingletonAsOn[T](defaultContainer, factoryFN)
+}
+
+// SingletonAsOn adds a singleton provider bound to T in the given container.
+func SingletonAsOn[T any](c *Container, factoryFN any) {
+	c.registerProvider(factoryFN, singleton, reflect.TypeFor[T](), "")
+}
+
+// Scoped adds a provider that caches one instance per scope (see NewScope).
+func Scoped(factoryFN any) {
+	defaultContainer.Scoped(factoryFN)
+}
+
+// ScopedAs adds a scoped provider bound to a specific interface or type T.
+func ScopedAs[T any](factoryFN any) {
+	ScopedAsOn[T](defaultContainer, factoryFN)
+}
+
+// ScopedAsOn adds a scoped provider bound to T in the given container.
+func ScopedAsOn[T any](c *Container, factoryFN any) {
+	c.registerProvider(factoryFN, scoped, reflect.TypeFor[T](), "")
+}
+
+// RegisterNamed adds a transient provider for T under a name, alongside other providers of the same type.
+func RegisterNamed[T any](name string, factoryFN any) {
+	RegisterNamedOn[T](defaultContainer, name, factoryFN)
+}
+
+// RegisterNamedOn adds a named transient provider for T in the given container.
+func RegisterNamedOn[T any](c *Container, name string, factoryFN any) {
+	c.registerProvider(factoryFN, transient, reflect.TypeFor[T](), name)
+}
+
+// SingletonNamed adds a singleton provider for T under a name, e.g. one per database.
+func SingletonNamed[T any](name string, factoryFN any) {
+	SingletonNamedOn[T](defaultContainer, name, factoryFN)
+}
+
+// SingletonNamedOn adds a named singleton provider for T in the given container.
+func SingletonNamedOn[T any](c *Container, name string, factoryFN any) {
+	c.registerProvider(factoryFN, singleton, reflect.TypeFor[T](), name)
+}
+
+// ScopedNamed adds a scoped provider for T under a name.
+func ScopedNamed[T any](name string, factoryFN any) {
+	ScopedNamedOn[T](defaultContainer, name, factoryFN)
 }
 
-// Resolve retrieves the primary instance for type T. Panics if no provider is found.
+// ScopedNamedOn adds a named scoped provider for T in the given container.
+func ScopedNamedOn[T any](c *Container, name string, factoryFN any) {
+	c.registerProvider(factoryFN, scoped, reflect.TypeFor[T](), name)
+}
+
+// Resolve retrieves the primary instance for type T. Panics if it cannot be resolved.
 func Resolve[T any]() T {
-	targetType := reflect.TypeFor[T]()
-	value := resolveByType(targetType)
-	return value.Interface().(T)
+	return ResolveOn[T](defaultContainer)
+}
+
+// ResolveOn retrieves the primary instance for type T from the given container.
+func ResolveOn[T any](c *Container) T {
+	return must(resolveAs[T](c, ""))
+}
+
+// TryResolve retrieves the primary instance for type T, returning a *ResolutionError
+// (with the full dependency chain) instead of panicking.
+func TryResolve[T any]() (T, error) {
+	return TryResolveOn[T](defaultContainer)
+}
+
+// TryResolveOn retrieves the primary instance for type T from the given container.
+func TryResolveOn[T any](c *Container) (T, error) {
+	return resolveAs[T](c, "")
+}
+
+// ResolveNamed retrieves the instance of T registered under name. Panics if it cannot be resolved.
+// Factories request named instances with a parameter struct (see In).
+func ResolveNamed[T any](name string) T {
+	return ResolveNamedOn[T](defaultContainer, name)
+}
+
+// ResolveNamedOn retrieves the instance of T registered under name from the given container.
+func ResolveNamedOn[T any](c *Container, name string) T {
+	return must(resolveAs[T](c, name))
+}
+
+// TryResolveNamed retrieves the instance of T registered under name, returning an error instead of panicking.
+func TryResolveNamed[T any](name string) (T, error) {
+	return TryResolveNamedOn[T](defaultContainer, name)
+}
+
+// TryResolveNamedOn retrieves the instance of T registered under name from the given container.
+func TryResolveNamedOn[T any](c *Container, name string) (T, error) {
+	return resolveAs[T](c, name)
 }
 
 // ResolveAll retrieves all registered providers for type T as a slice.
 func ResolveAll[T any]() []T {
-	targetType := reflect.TypeFor[T]()
-
-	registryMutex.RLock()
-	providers := providerRegistry[targetType]
-	registryMutex.RUnlock()
+	return ResolveAllOn[T](defaultContainer)
+}
 
-	if len(providers) == 0 {
-		return nil
-	}
+// ResolveAllOn retrieves all providers for type T from the given container.
+func ResolveAllOn[T any](c *Container) []T {
+	return must(resolveAllAs[T](c))
+}
 
-	results := make([]T, 0, len(providers))
-	for _, providerInstance := range providers {
-		value := buildInstance(providerInstance)
-		results = append(results, value.Interface().(T))
-	}
+// TryResolveAll retrieves all registered providers for type T, returning the first failure as an error.
+func TryResolveAll[T any]() ([]T, error) {
+	return TryResolveAllOn[T](defaultContainer)
+}
 
-	return results
+// TryResolveAllOn retrieves all providers for type T from the given container.
+func TryResolveAllOn[T any](c *Container) ([]T, error) {
+	return resolveAllAs[T](c)
 }
@@ -1,8 +1,15 @@
 package di
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // --- Mocks and Types for Testing ---
@@ -45,9 +52,10 @@ func NewCalculator(cfg *Config) *Calculator {
 // Since providerRegistry is private, we implement a Reset function
 // to ensure test isolation.
 func resetRegistry() {
-	registryMutex.Lock()
-	defer registryMutex.Unlock()
-	providerRegistry = make(map[reflect.Type][]*Provider)
+	defaultContainer.mutex.Lock()
+	defer defaultContainer.mutex.Unlock()
+	defaultContainer.providers = make(map[providerKey][]*Provider)
+	defaultContainer.built = nil
 }
 
 // --- Test Cases ---
@@ -158,10 +166,840 @@ func TestDI_Panics(t *testing.T) {
 		resetRegistry()
 		defer func() {
 			if r := recover(); r == nil {
-				t.Error("Should have panicked when factory returns more than one value")
+				t.Error("Should have panicked when factory returns a second value that is not an error")
 			}
 		}()
-		multiReturnFactory := func() (*Config, error) { return &Config{}, nil }
+		multiReturnFactory := func() (*Config, *Circle) { return &Config{}, nil }
 		Register(multiReturnFactory)
 	})
 }
+
+func TestDI_Container(t *testing.T) {
+	resetRegistry()
+
+	container := New()
+	container.Singleton(NewConfig)
+	RegisterAsOn[Shape](container, NewCircle)
+
+	if ResolveOn[*Config](container) != ResolveOn[*Config](container) {
+		t.Error("Singleton failed inside an isolated container")
+	}
+	if shapes := ResolveAllOn[Shape](container); len(shapes) != 1 {
+		t.Errorf("Expected 1 shape in the container, got %d", len(shapes))
+	}
+	if shapes := ResolveAll[Shape](); len(shapes) != 0 {
+		t.Errorf("Expected the default container to stay empty, got %d shapes", len(shapes))
+	}
+
+	container.Reset()
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Should have panicked after resetting the container")
+		}
+	}()
+	ResolveOn[*Config](container)
+}
+
+type Transaction struct {
+	Config *Config
+	closed bool
+}
+
+func (tx *Transaction) Close() error {
+	tx.closed = true
+	return nil
+}
+
+type Repository struct {
+	Tx *Transaction
+}
+
+func TestDI_Scoped(t *testing.T) {
+	resetRegistry()
+
+	Singleton(NewConfig)
+	Scoped(func(cfg *Config) *Transaction { return &Transaction{Config: cfg} })
+	Register(func(tx *Transaction) *Repository { return &Repository{Tx: tx} })
+
+	t.Run("Share instances within a scope", func(t *testing.T) {
+		scope := NewScope()
+		repo1 := ResolveOn[*Repository](scope)
+		repo2 := ResolveOn[*Repository](scope)
+
+		if repo1 == repo2 {
+			t.Error("Transient failed: repositories should be different instances")
+		}
+		if repo1.Tx != repo2.Tx {
+			t.Error("Scoped failed: repositories should share the scope transaction")
+		}
+		if repo1.Tx.Config != Resolve[*Config]() {
+			t.Error("Scoped instances should get singletons from the parent")
+		}
+
+		other := ResolveOn[*Transaction](NewScope())
+		if other == repo1.Tx {
+			t.Error("Scoped failed: different scopes should have different instances")
+		}
+	})
+
+	t.Run("Release instances on close", func(t *testing.T) {
+		scope := NewScope()
+		tx := ResolveOn[*Transaction](scope)
+
+		if err := scope.Close(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !tx.closed {
+			t.Error("Expected the scoped instance to be closed")
+		}
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Should have panicked when resolving from a closed scope")
+			}
+		}()
+		ResolveOn[*Transaction](scope)
+	})
+
+	t.Run("Panic on scoped resolution from the root", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Should have panicked when resolving a scoped service from the root")
+			}
+		}()
+		Resolve[*Repository]()
+	})
+}
+
+type UserRepo struct{ Config *Config }
+type UserService struct{ Repo *UserRepo }
+
+func TestDI_TryResolve(t *testing.T) {
+	t.Run("Report the dependency chain", func(t *testing.T) {
+		resetRegistry()
+		Register(func(cfg *Config) *UserRepo { return &UserRepo{Config: cfg} })
+		Register(func(repo *UserRepo) *UserService { return &UserService{Repo: repo} })
+
+		_, err := TryResolve[*UserService]()
+		if !errors.Is(err, ErrNoProvider) {
+			t.Fatalf("Expected ErrNoProvider, got %v", err)
+		}
+		expected := "di: *di.UserService -> *di.UserRepo -> *di.Config: no provider registered"
+		if err.Error() != expected {
+			t.Errorf("Expected %q, got %q", expected, err.Error())
+		}
+	})
+
+	t.Run("Report factory errors", func(t *testing.T) {
+		resetRegistry()
+		failure := errors.New("connection refused")
+		Singleton(func() (*Config, error) { return nil, failure })
+		Register(func(cfg *Config) *UserRepo { return &UserRepo{Config: cfg} })
+
+		_, err := TryResolve[*UserRepo]()
+		var resolutionErr *ResolutionError
+		if !errors.As(err, &resolutionErr) || !errors.Is(err, failure) {
+			t.Fatalf("Expected a ResolutionError wrapping the factory error, got %v", err)
+		}
+		if len(resolutionErr.Chain) != 2 {
+			t.Errorf("Expected a chain of 2 types, got %v", resolutionErr.Chain)
+		}
+
+		if _, err := TryResolveAll[*UserRepo](); !errors.Is(err, failure) {
+			t.Errorf("Expected the factory error from TryResolveAll, got %v", err)
+		}
+	})
+
+	t.Run("Resolve factories returning errors", func(t *testing.T) {
+		resetRegistry()
+		Singleton(func() (*Config, error) { return &Config{Factor: 3}, nil })
+
+		cfg, err := TryResolve[*Config]()
+		if err != nil || cfg.Factor != 3 {
+			t.Errorf("Expected factor 3 without error, got %v and %v", cfg, err)
+		}
+	})
+
+	t.Run("Report scoped resolution outside of a scope", func(t *testing.T) {
+		resetRegistry()
+		Scoped(NewConfig)
+
+		if _, err := TryResolve[*Config](); !errors.Is(err, ErrScopeRequired) {
+			t.Errorf("Expected ErrScopeRequired, got %v", err)
+		}
+	})
+}
+
+type Ping struct{ Pong *Pong }
+type Pong struct{ Ping *Ping }
+
+func TestDI_Cycles(t *testing.T) {
+	t.Run("Build singletons with dependencies concurrently", func(t *testing.T) {
+		resetRegistry()
+		var calls atomic.Int32
+		Singleton(NewConfig)
+		Singleton(func(cfg *Config) *Calculator {
+			calls.Add(1)
+			return NewCalculator(cfg)
+		})
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() { Resolve[*Calculator]() })
+		}
+		wg.Wait()
+
+		if calls.Load() != 1 {
+			t.Errorf("Expected the singleton factory to run once, ran %d times", calls.Load())
+		}
+	})
+
+	t.Run("Report the cycle chain", func(t *testing.T) {
+		resetRegistry()
+		Singleton(func(pong *Pong) *Ping { return &Ping{Pong: pong} })
+		Register(func(ping *Ping) *Pong { return &Pong{Ping: ping} })
+
+		_, err := TryResolve[*Ping]()
+		if !errors.Is(err, ErrCycle) {
+			t.Fatalf("Expected ErrCycle, got %v", err)
+		}
+		expected := "di: *di.Ping -> *di.Pong -> *di.Ping: dependency cycle detected"
+		if err.Error() != expected {
+			t.Errorf("Expected %q, got %q", expected, err.Error())
+		}
+	})
+
+	t.Run("Report cycles resolved concurrently from both ends", func(t *testing.T) {
+		for range 20 {
+			container := New()
+			container.Singleton(func() *Config {
+				time.Sleep(time.Millisecond)
+				return NewConfig()
+			})
+			container.Singleton(func(cfg *Config, pong *Pong) *Ping { return &Ping{Pong: pong} })
+			container.Singleton(func(cfg *Config, ping *Ping) *Pong { return &Pong{Ping: ping} })
+
+			errs := make(chan error, 2)
+			go func() { _, err := TryResolveOn[*Ping](container); errs <- err }()
+			go func() { _, err := TryResolveOn[*Pong](container); errs <- err }()
+
+			for range 2 {
+				select {
+				case err := <-errs:
+					if !errors.Is(err, ErrCycle) {
+						t.Fatalf("Expected ErrCycle, got %v", err)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("Concurrent resolution of a cycle deadlocked")
+				}
+			}
+		}
+	})
+}
+
+func TestDI_Validate(t *testing.T) {
+	t.Run("Accept a resolvable graph", func(t *testing.T) {
+		resetRegistry()
+		Singleton(NewConfig)
+		Singleton(NewCalculator)
+		Scoped(func(cfg *Config) *Transaction { return &Transaction{Config: cfg} })
+		Register(func(tx *Transaction) *Repository { return &Repository{Tx: tx} })
+
+		if err := Validate(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Report every problem without calling factories", func(t *testing.T) {
+		resetRegistry()
+		called := false
+		Register(func() *Config { called = true; return &Config{} })
+		Singleton(func(cfg *Config) *Calculator { called = true; return NewCalculator(cfg) })
+		Register(func(repo *UserRepo) *UserService { called = true; return &UserService{Repo: repo} })
+		Singleton(func(pong *Pong) *Ping { called = true; return &Ping{Pong: pong} })
+		Singleton(func(ping *Ping) *Pong { called = true; return &Pong{Ping: ping} })
+
+		err := Validate()
+		if called {
+			t.Error("Validate should not call any factory")
+		}
+		for _, target := range []error{ErrNoProvider, ErrCycle, ErrLifetimeMismatch} {
+			if !errors.Is(err, target) {
+				t.Errorf("Expected the error to contain %v, got %v", target, err)
+			}
+		}
+
+		expected := "di: *di.Calculator -> *di.Config: lifetime mismatch: singleton depends on transient\n" +
+			"di: *di.Ping -> *di.Pong -> *di.Ping: dependency cycle detected\n" +
+			"di: *di.UserService -> *di.UserRepo: no provider registered"
+		if err.Error() != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, err.Error())
+		}
+	})
+}
+
+type Broker struct {
+	name   string
+	events *[]string
+	failOn string
+}
+
+func (b *Broker) Start(ctx context.Context) error {
+	if b.failOn == "start" {
+		return errors.New("unreachable")
+	}
+	*b.events = append(*b.events, "start "+b.name)
+	return nil
+}
+
+func (b *Broker) Close() error {
+	*b.events = append(*b.events, "close "+b.name)
+	return nil
+}
+
+type Consumer struct{ Broker *Broker }
+
+func (c *Consumer) Start(ctx context.Context) error {
+	*c.Broker.events = append(*c.Broker.events, "start consumer")
+	return nil
+}
+
+func (c *Consumer) Stop(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDI_Lifecycle(t *testing.T) {
+	t.Run("Start and stop in dependency order", func(t *testing.T) {
+		resetRegistry()
+		var events []string
+		Singleton(func(broker *Broker) *Consumer { return &Consumer{Broker: broker} })
+		Singleton(func() *Broker { return &Broker{name: "broker", events: &events} })
+
+		if err := Start(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := []string{"start broker", "start consumer"}
+		if !reflect.DeepEqual(events, expected) {
+			t.Errorf("Expected %v, got %v", expected, events)
+		}
+
+		// The consumer blocks on stop until the deadline
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := Stop(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "di: stop *di.Consumer") {
+			t.Errorf("Expected the consumer to time out, got %v", err)
+		}
+	})
+
+	t.Run("Stop what was built when start fails", func(t *testing.T) {
+		resetRegistry()
+		var events []string
+		Singleton(func() *Broker { return &Broker{name: "broker", events: &events, failOn: "start"} })
+
+		err := Start(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "di: start *di.Broker: unreachable") {
+			t.Errorf("Expected the start error, got %v", err)
+		}
+		if !reflect.DeepEqual(events, []string{"close broker"}) {
+			t.Errorf("Expected the broker to be closed, got %v", events)
+		}
+	})
+}
+
+type ReplicaParams struct {
+	In
+	Primary *Config `di:"name=primary"`
+	Replica *Config `di:"name=replica"`
+}
+
+func TestDI_Named(t *testing.T) {
+	t.Run("Resolve providers of the same type by name", func(t *testing.T) {
+		resetRegistry()
+		SingletonNamed[*Config]("primary", func() *Config { return &Config{Factor: 1} })
+		SingletonNamed[*Config]("replica", func() *Config { return &Config{Factor: 2} })
+		Register(func(params ReplicaParams) *Calculator { return NewCalculator(params.Replica) })
+
+		if ResolveNamed[*Config]("primary").Factor != 1 || ResolveNamed[*Config]("replica").Factor != 2 {
+			t.Error("Expected each name to resolve its own provider")
+		}
+		if Resolve[*Calculator]().Config.Factor != 2 {
+			t.Error("Expected the parameter struct to receive the replica")
+		}
+		if _, err := TryResolve[*Config](); !errors.Is(err, ErrNoProvider) {
+			t.Errorf("Expected named providers to be hidden from unnamed resolution, got %v", err)
+		}
+	})
+
+	t.Run("Report missing names", func(t *testing.T) {
+		resetRegistry()
+		SingletonNamed[*Config]("primary", NewConfig)
+		Register(func(params ReplicaParams) *Calculator { return NewCalculator(params.Replica) })
+
+		_, err := TryResolve[*Calculator]()
+		expected := "di: *di.Calculator -> *di.Config[name=replica]: no provider registered"
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected %q, got %v", expected, err)
+		}
+		if err := Validate(); err == nil || err.Error() != expected {
+			t.Errorf("Expected Validate to report %q, got %v", expected, err)
+		}
+	})
+
+	t.Run("Panic on unknown tag options", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Should have panicked on an unknown tag option")
+			}
+		}()
+		type BadParams struct {
+			In
+			Config *Config `di:"label=replica"`
+		}
+		Register(func(params BadParams) *Calculator { return NewCalculator(params.Config) })
+	})
+}
+
+type HealthCheck interface{ Name() string }
+
+type namedCheck string
+
+func (check namedCheck) Name() string { return string(check) }
+
+type Databases struct {
+	Out
+	Primary *Config     `di:"name=primary"`
+	Replica *Config     `di:"name=replica"`
+	Check   HealthCheck `di:"group=health"`
+}
+
+type MonitorParams struct {
+	In
+	Replica    *Config       `di:"name=replica"`
+	Calculator *Calculator   `di:"optional"`
+	Checks     []HealthCheck `di:"group=health"`
+}
+
+type Monitor struct {
+	Replica    *Config
+	Calculator *Calculator
+	Checks     []HealthCheck
+}
+
+func TestDI_InOut(t *testing.T) {
+	t.Run("Register the fields of an Out struct", func(t *testing.T) {
+		resetRegistry()
+		calls := 0
+		Singleton(func() (Databases, error) {
+			calls++
+			return Databases{Primary: &Config{Factor: 1}, Replica: &Config{Factor: 2}, Check: namedCheck("db")}, nil
+		})
+		Singleton(func() Databases { return Databases{Check: namedCheck("cache")} })
+		Register(func(params MonitorParams) *Monitor {
+			return &Monitor{Replica: params.Replica, Calculator: params.Calculator, Checks: params.Checks}
+		})
+
+		monitor := Resolve[*Monitor]()
+		if monitor.Replica != ResolveNamed[*Config]("replica") || ResolveNamed[*Config]("primary").Factor != 1 {
+			t.Error("Expected the named fields to be singletons of the Out factory")
+		}
+		if calls != 1 {
+			t.Errorf("Expected the Out factory to run once, ran %d times", calls)
+		}
+		if monitor.Calculator != nil {
+			t.Error("Expected the optional dependency to be nil")
+		}
+		if len(monitor.Checks) != 2 || monitor.Checks[0].Name() != "db" || monitor.Checks[1].Name() != "cache" {
+			t.Errorf("Expected both health checks in registration order, got %v", monitor.Checks)
+		}
+		if err := Validate(); err != nil {
+			t.Errorf("Expected a valid graph, got %v", err)
+		}
+	})
+
+	t.Run("Resolve optional dependencies when registered", func(t *testing.T) {
+		resetRegistry()
+		Singleton(NewConfig)
+		Register(NewCalculator)
+		RegisterNamed[*Config]("replica", NewConfig)
+		Register(func(params MonitorParams) *Monitor { return &Monitor{Calculator: params.Calculator} })
+
+		if monitor := Resolve[*Monitor](); monitor.Calculator == nil || monitor.Checks != nil {
+			t.Errorf("Expected the calculator and no health checks, got %+v", monitor)
+		}
+	})
+
+	t.Run("Panic on optional Out fields", func(t *testing.T) {
+		resetRegistry()
+		defer func() {
+			if recover() == nil {
+				t.Error("Should have panicked on an optional Out field")
+			}
+			if _, err := TryResolve[*Config](); !errors.Is(err, ErrNoProvider) {
+				t.Error("Expected nothing to be registered")
+			}
+		}()
+		type BadOut struct {
+			Out
+			Config *Config
+			Circle *Circle `di:"optional"`
+		}
+		Register(func() BadOut { return BadOut{} })
+	})
+}
+
+type Dashboard struct {
+	Shapes     []Shape
+	Calculator Optional[*Calculator]
+}
+
+func TestDI_OptionalAndSlices(t *testing.T) {
+	t.Run("Resolve slices to every provider and optionals to empty", func(t *testing.T) {
+		resetRegistry()
+		RegisterAs[Shape](NewCircle)
+		RegisterAs[Shape](func() *Circle { return &Circle{Radius: 2} })
+		Register(func(shapes []Shape, calculator Optional[*Calculator]) *Dashboard {
+			return &Dashboard{Shapes: shapes, Calculator: calculator}
+		})
+
+		dashboard := Resolve[*Dashboard]()
+		if len(dashboard.Shapes) != 2 {
+			t.Errorf("Expected 2 shapes, got %d", len(dashboard.Shapes))
+		}
+		if calculator, ok := dashboard.Calculator.Get(); ok || calculator != nil {
+			t.Error("Expected the optional calculator to be empty")
+		}
+		if err := Validate(); err != nil {
+			t.Errorf("Expected a valid graph, got %v", err)
+		}
+	})
+
+	t.Run("Resolve registered optionals", func(t *testing.T) {
+		resetRegistry()
+		Singleton(NewConfig)
+		Singleton(NewCalculator)
+		Register(func(calculator Optional[*Calculator]) *Dashboard { return &Dashboard{Calculator: calculator} })
+
+		calculator, ok := Resolve[*Dashboard]().Calculator.Get()
+		if !ok || calculator != Resolve[*Calculator]() {
+			t.Error("Expected the optional to hold the calculator singleton")
+		}
+	})
+
+	t.Run("Report failures of registered optionals", func(t *testing.T) {
+		resetRegistry()
+		Register(NewCalculator)
+		Register(func(calculator Optional[*Calculator]) *Dashboard { return &Dashboard{Calculator: calculator} })
+
+		if _, err := TryResolve[*Dashboard](); !errors.Is(err, ErrNoProvider) {
+			t.Errorf("Expected the missing config to be reported, got %v", err)
+		}
+	})
+}
+
+type ScaledShape struct {
+	Inner  Shape
+	Factor float64
+}
+
+func (s *ScaledShape) Area() float64 { return s.Inner.Area() * s.Factor }
+
+func TestDI_Decorate(t *testing.T) {
+	t.Run("Stack decorators in registration order", func(t *testing.T) {
+		resetRegistry()
+		Singleton(func() *Config { return &Config{Factor: 2} })
+		RegisterAs[Shape](func() *Circle { return &Circle{Radius: 1} })
+		Decorate[Shape](func(inner Shape, cfg *Config) Shape { return &ScaledShape{Inner: inner, Factor: float64(cfg.Factor)} })
+		Decorate[Shape](func(inner Shape) (Shape, error) { return &ScaledShape{Inner: inner, Factor: 10}, nil })
+
+		shape, ok := Resolve[Shape]().(*ScaledShape)
+		if !ok || shape.Factor != 10 || shape.Inner.(*ScaledShape).Factor != 2 {
+			t.Fatalf("Expected the last decorator to be outermost, got %#v", shape)
+		}
+		if Resolve[Shape]() == Shape(shape) {
+			t.Error("Expected the transient to be decorated on every resolution")
+		}
+	})
+
+	t.Run("Decorate singletons once", func(t *testing.T) {
+		resetRegistry()
+		calls := 0
+		SingletonAs[Shape](NewCircle)
+		Decorate[Shape](func(inner Shape) Shape {
+			calls++
+			return &ScaledShape{Inner: inner, Factor: 2}
+		})
+
+		if Resolve[Shape]() != Resolve[Shape]() || calls != 1 {
+			t.Errorf("Expected the decorated singleton to be built once, decorated %d times", calls)
+		}
+	})
+
+	t.Run("Report decorator failures", func(t *testing.T) {
+		resetRegistry()
+		RegisterAs[Shape](NewCircle)
+		Decorate[Shape](func(inner Shape, cfg *Config) Shape { return inner })
+
+		if _, err := TryResolve[Shape](); !errors.Is(err, ErrNoProvider) {
+			t.Errorf("Expected the missing decorator dependency to be reported, got %v", err)
+		}
+		if err := Validate(); !errors.Is(err, ErrNoProvider) {
+			t.Errorf("Expected Validate to report the missing decorator dependency, got %v", err)
+		}
+	})
+
+	t.Run("Leave the parent undecorated", func(t *testing.T) {
+		parent := New()
+		SingletonAsOn[Shape](parent, NewCircle)
+		parent.Singleton(func(shape Shape) *ShapeHolder { return &ShapeHolder{Shape: shape} })
+
+		child := parent.Child()
+		DecorateOn[Shape](child, func(inner Shape) Shape { return &ScaledShape{Inner: inner, Factor: 2} })
+		scope := parent.NewScope()
+		DecorateOn[Shape](scope, func(inner Shape) Shape { return &ScaledShape{Inner: inner, Factor: 3} })
+
+		if _, ok := ResolveOn[Shape](parent).(*Circle); !ok {
+			t.Errorf("Expected the parent to stay undecorated, got %#v", ResolveOn[Shape](parent))
+		}
+		if _, ok := ResolveOn[*ShapeHolder](parent).Shape.(*Circle); !ok {
+			t.Error("Expected the parent singletons to stay undecorated")
+		}
+		if shape, ok := ResolveOn[Shape](child).(*ScaledShape); !ok || shape.Factor != 2 {
+			t.Errorf("Expected the child to be decorated, got %#v", ResolveOn[Shape](child))
+		}
+		if shape, ok := ResolveOn[*ShapeHolder](child).Shape.(*ScaledShape); !ok || shape.Factor != 2 {
+			t.Error("Expected the child singletons to receive the decorated provider")
+		}
+		if shape, ok := ResolveOn[Shape](scope).(*ScaledShape); !ok || shape.Factor != 3 {
+			t.Errorf("Expected the scope to be decorated by its own decorator only, got %#v", ResolveOn[Shape](scope))
+		}
+		if _, ok := ResolveOn[Shape](parent.Child()).(*Circle); !ok {
+			t.Error("Expected sibling containers to stay undecorated")
+		}
+	})
+
+	t.Run("Panic on invalid decorators", func(t *testing.T) {
+		resetRegistry()
+		RegisterAs[Shape](NewCircle)
+
+		assertPanics := func(name string, decorate func()) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Should have panicked on %s", name)
+				}
+			}()
+			decorate()
+		}
+		assertPanics("a type without providers", func() {
+			Decorate[*Config](func(inner *Config) *Config { return inner })
+		})
+		assertPanics("a different return type", func() {
+			Decorate[Shape](func(inner Shape) *Circle { return nil })
+		})
+		assertPanics("a singleton already built", func() {
+			SingletonAs[Mailer](func() Mailer { return smtpMailer{} })
+			Resolve[Mailer]()
+			Decorate[Mailer](func(inner Mailer) Mailer { return fakeMailer{} })
+		})
+		if _, ok := Resolve[Mailer]().(smtpMailer); !ok {
+			t.Error("Expected the built singleton to be left as is")
+		}
+	})
+}
+
+type ShapeHolder struct{ Shape Shape }
+
+type Mailer interface{ Send(to string) string }
+
+type smtpMailer struct{}
+
+func (smtpMailer) Send(to string) string { return "smtp:" + to }
+
+type fakeMailer struct{}
+
+func (fakeMailer) Send(to string) string { return "fake:" + to }
+
+type Notifier struct{ Mailer Mailer }
+
+func registerNotifier(c *Container) {
+	c.Singleton(NewConfig)
+	SingletonAsOn[Mailer](c, func(cfg *Config) Mailer { return smtpMailer{} })
+	c.Register(func(mailer Mailer) *Repository { return &Repository{} })
+	c.Singleton(func(mailer Mailer, repo *Repository) *Notifier { return &Notifier{Mailer: mailer} })
+}
+
+func TestDI_Override(t *testing.T) {
+	t.Run("Override in a child container", func(t *testing.T) {
+		t.Parallel()
+		parent := New()
+		registerNotifier(parent)
+		original := ResolveOn[*Notifier](parent)
+
+		child := parent.Child()
+		OverrideOn[Mailer](child, func() Mailer { return fakeMailer{} })
+
+		notifier := ResolveOn[*Notifier](child)
+		if notifier.Mailer.Send("a") != "fake:a" || notifier != ResolveOn[*Notifier](child) {
+			t.Error("Expected the singleton to be rebuilt once with the fake")
+		}
+		if ResolveOn[*Notifier](parent) != original || original.Mailer.Send("a") != "smtp:a" {
+			t.Error("Expected the parent to keep its singleton")
+		}
+		if ResolveOn[*Config](child) != ResolveOn[*Config](parent) {
+			t.Error("Expected singletons unrelated to the override to be shared")
+		}
+	})
+
+	t.Run("Override for the duration of a test", func(t *testing.T) {
+		resetRegistry()
+		registerNotifier(defaultContainer)
+		original := Resolve[*Notifier]()
+
+		t.Run("with fake", func(t *testing.T) {
+			Override[Mailer](t, func() Mailer { return fakeMailer{} })
+			if Resolve[*Notifier]().Mailer.Send("a") != "fake:a" {
+				t.Error("Expected the singleton to be rebuilt with the fake")
+			}
+		})
+
+		if Resolve[*Notifier]() != original {
+			t.Error("Expected the original singleton to be restored")
+		}
+	})
+}
+
+func TestDI_Graph(t *testing.T) {
+	resetRegistry()
+	Singleton(NewConfig)
+	Register(NewCalculator)
+	Register(func(calculator Optional[*Calculator], repo *UserRepo) *Dashboard { return &Dashboard{} })
+	Resolve[*Config]()
+
+	graph := Graph()
+
+	expectedDOT := `digraph di {
+	rankdir=LR;
+	"*di.Calculator" [label="*di.Calculator\ntransient"];
+	"*di.Config" [label="*di.Config\nsingleton (built)", style=bold];
+	"*di.Dashboard" [label="*di.Dashboard\ntransient"];
+	"*di.UserRepo" [label="*di.UserRepo\nmissing", style=dashed, color=red];
+	"*di.Calculator" -> "*di.Config";
+	"*di.Dashboard" -> "*di.Calculator" [style=dashed];
+	"*di.Dashboard" -> "*di.UserRepo";
+}
+`
+	if dot := graph.DOT(); dot != expectedDOT {
+		t.Errorf("Expected DOT:\n%s\ngot:\n%s", expectedDOT, dot)
+	}
+
+	expectedMermaid := `flowchart LR
+	n0["*di.Calculator<br/>transient"]
+	n1["*di.Config<br/>singleton (built)"]
+	n2["*di.Dashboard<br/>transient"]
+	n3["*di.UserRepo<br/>missing"]
+	n0 --> n1
+	n2 -.-> n0
+	n2 --> n3
+`
+	if mermaid := graph.Mermaid(); mermaid != expectedMermaid {
+		t.Errorf("Expected Mermaid:\n%s\ngot:\n%s", expectedMermaid, mermaid)
+	}
+
+	data, err := graph.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DependencyGraph
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(&decoded, graph) {
+		t.Errorf("Expected the JSON to round-trip, got %s", data)
+	}
+}
+
+type Probes struct {
+	Out
+	Health HealthCheck `di:"group=health"`
+	Ready  HealthCheck `di:"group=ready"`
+	Live   HealthCheck `di:"group=live"`
+}
+
+func TestDI_GraphDeterministic(t *testing.T) {
+	resetRegistry()
+	Singleton(func() Probes { return Probes{} })
+	Register(func(checks []HealthCheck) *Monitor { return &Monitor{Checks: checks} })
+
+	expected := Graph().DOT()
+	for range 50 {
+		if dot := Graph().DOT(); dot != expected {
+			t.Fatalf("Expected the same graph on every call, got:\n%s\nthen:\n%s", expected, dot)
+		}
+	}
+	if report := Validate(); report != nil {
+		t.Errorf("Expected a valid graph, got %v", report)
+	}
+}
+
+type LazyPing struct{ Pong Lazy[*LazyPong] }
+type LazyPong struct{ Ping *LazyPing }
+
+type ShapeFactory struct {
+	NewCircle func() *Circle
+	NewShape  func() (Shape, error)
+}
+
+func TestDI_Lazy(t *testing.T) {
+	t.Run("Break construction cycles with Lazy", func(t *testing.T) {
+		resetRegistry()
+		calls := 0
+		Singleton(func(pong Lazy[*LazyPong]) *LazyPing { return &LazyPing{Pong: pong} })
+		Register(func(ping *LazyPing) *LazyPong {
+			calls++
+			return &LazyPong{Ping: ping}
+		})
+
+		ping := Resolve[*LazyPing]()
+		if calls != 0 {
+			t.Error("Expected the lazy dependency not to be built with its dependent")
+		}
+		if pong := ping.Pong.Get(); pong.Ping != ping || ping.Pong.Get() != pong || calls != 1 {
+			t.Error("Expected the lazy dependency to be resolved once, on the first Get")
+		}
+		if err := Validate(); err != nil {
+			t.Errorf("Expected the lazy cycle to be valid, got %v", err)
+		}
+	})
+
+	t.Run("Detect cycles when Get is called during construction", func(t *testing.T) {
+		resetRegistry()
+		Singleton(func(pong Lazy[*LazyPong]) (*LazyPing, error) {
+			_, err := pong.TryGet()
+			return &LazyPing{Pong: pong}, err
+		})
+		Register(func(ping *LazyPing) *LazyPong { return &LazyPong{Ping: ping} })
+
+		if _, err := TryResolve[*LazyPing](); !errors.Is(err, ErrCycle) {
+			t.Errorf("Expected ErrCycle, got %v", err)
+		}
+	})
+
+	t.Run("Inject resolver functions", func(t *testing.T) {
+		resetRegistry()
+		Register(NewCircle)
+		Register(func(newCircle func() *Circle, newShape func() (Shape, error)) *ShapeFactory {
+			return &ShapeFactory{NewCircle: newCircle, NewShape: newShape}
+		})
+
+		factory := Resolve[*ShapeFactory]()
+		if factory.NewCircle() == factory.NewCircle() {
+			t.Error("Expected every call to resolve a new transient")
+		}
+		if _, err := factory.NewShape(); !errors.Is(err, ErrNoProvider) {
+			t.Errorf("Expected the missing shape to be reported, got %v", err)
+		}
+
+		RegisterAs[Shape](NewCircle)
+		if shape, err := factory.NewShape(); err != nil || shape == nil {
+			t.Errorf("Expected the shape registered later to be resolved, got %v", err)
+		}
+	})
+}
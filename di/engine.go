@@ -1,53 +1,273 @@
 package di
 
 import (
-	"fmt"
 	"reflect"
+	"slices"
+	"sync/atomic"
 )
 
-// resolveByType finds the registered provider and triggers the build process.
-func resolveByType(targetType reflect.Type) reflect.Value {
-	registryMutex.RLock()
-	providers := providerRegistry[targetType]
-	registryMutex.RUnlock()
+var errorType = reflect.TypeFor[error]()
 
+// resolveKey finds the registered provider and triggers the build process.
+// chain holds the keys being resolved above this one, for error reporting and cycle detection.
+func (c *Container) resolveKey(key providerKey, chain []providerKey) (reflect.Value, error) {
+	cyclic := slices.Contains(chain, key)
+	chain = append(slices.Clip(chain), key)
+	if cyclic {
+		return reflect.Value{}, newResolutionError(chain, ErrCycle)
+	}
+
+	providers := c.lookup(key)
 	if len(providers) == 0 {
-		panic(fmt.Sprintf("di: no provider registered for type %v", targetType))
+		return reflect.Value{}, newResolutionError(chain, ErrNoProvider)
 	}
 
-	return buildInstance(providers[0])
+	return c.buildInstance(providers[0], chain)
 }
 
-// buildInstance manages the lifecycle of the instance (Transient vs Singleton).
-func buildInstance(providerInstance *Provider) reflect.Value {
+// buildInstance manages the lifecycle of the instance (Transient, Singleton or Scoped).
+// Singletons resolve their dependencies from the container they were registered in,
+// so they never capture instances of a scope.
+func (c *Container) buildInstance(providerInstance *Provider, chain []providerKey) (reflect.Value, error) {
 	if providerInstance.IsSingleton {
-		registryMutex.Lock()
-		defer registryMutex.Unlock()
+		return providerInstance.buildSingleton(chain)
+	}
+
+	if providerInstance.IsScoped {
+		return c.buildScoped(providerInstance, chain)
+	}
+
+	return c.callFactoryWithDependencies(providerInstance, chain)
+}
 
-		if providerInstance.CachedInstance.IsValid() {
-			return providerInstance.CachedInstance
+// buildSingleton builds the singleton once. Only the provider's own lock is held while the factory runs,
+// so concurrent resolutions of other types never wait on it. A failed build is not cached.
+func (providerInstance *Provider) buildSingleton(chain []providerKey) (reflect.Value, error) {
+	// The chain only sees this goroutine: two goroutines building a cycle from both ends would
+	// each hold the lock the other waits for, so cycles are looked for before taking it
+	if !providerInstance.built.Load() {
+		if cycle := providerInstance.cycle(); cycle != nil {
+			if len(chain) == 0 || chain[len(chain)-1] != providerInstance.key() {
+				chain = append(slices.Clip(chain), providerInstance.key())
+			}
+			return reflect.Value{}, newResolutionError(append(slices.Clip(chain), cycle...), ErrCycle)
 		}
+	}
 
-		instance := callFactoryWithDependencies(providerInstance)
-		providerInstance.CachedInstance = instance
-		return instance
+	providerInstance.buildMutex.Lock()
+	defer providerInstance.buildMutex.Unlock()
+
+	if providerInstance.CachedInstance.IsValid() {
+		return providerInstance.CachedInstance, nil
+	}
+
+	instance, err := providerInstance.container.callFactoryWithDependencies(providerInstance, chain)
+	if err != nil {
+		return reflect.Value{}, err
 	}
+	providerInstance.CachedInstance = instance
+	providerInstance.built.Store(true)
+
+	// Dependencies finish building first, so this order is the dependency order used by Start
+	owner := providerInstance.container
+	owner.mutex.Lock()
+	owner.built = append(owner.built, providerInstance)
+	owner.mutex.Unlock()
+	return instance, nil
+}
+
+// cycle returns the keys leading from the provider back to itself through the dependencies built with it,
+// or nil. Deferred dependencies are built after the provider, so they cannot close a cycle.
+func (providerInstance *Provider) cycle() []providerKey {
+	visited := make(map[*Provider]bool)
+
+	var walk func(current *Provider, resolver *Container, path []providerKey) []providerKey
+	walk = func(current *Provider, resolver *Container, path []providerKey) []providerKey {
+		// Singletons resolve their dependencies from the container they were registered in
+		if current.IsSingleton {
+			resolver = current.container
+		}
+
+		next := make([]*Provider, 0, 1)
+		if current.source != nil {
+			next = append(next, current.source)
+		} else {
+			for _, dependency := range current.dependencies() {
+				_, providers, _, deferred := resolver.dependencyProviders(dependency)
+				if !deferred {
+					next = append(next, providers...)
+				}
+			}
+		}
 
-	return callFactoryWithDependencies(providerInstance)
+		for _, dependencyProvider := range next {
+			dependencyPath := append(slices.Clip(path), dependencyProvider.key())
+			if dependencyProvider == providerInstance {
+				return dependencyPath
+			}
+			if visited[dependencyProvider] {
+				continue
+			}
+			visited[dependencyProvider] = true
+			if found := walk(dependencyProvider, resolver, dependencyPath); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return walk(providerInstance, providerInstance.container, nil)
 }
 
-// callFactoryWithDependencies recursively resolves all inputs of a factory function.
-func callFactoryWithDependencies(providerInstance *Provider) reflect.Value {
-	factoryType := providerInstance.FactoryFunction.Type()
-	numberOfInputs := factoryType.NumIn()
+// callFactoryWithDependencies recursively resolves all inputs of a factory function,
+// then passes the instance through the provider's decorators.
+func (c *Container) callFactoryWithDependencies(providerInstance *Provider, chain []providerKey) (reflect.Value, error) {
+	var instance reflect.Value
+	var err error
 
-	arguments := make([]reflect.Value, numberOfInputs)
+	// Out fields read the struct built by their own factory, not the first provider of the struct type
+	if source := providerInstance.source; source != nil {
+		instance, err = c.buildInstance(source, append(slices.Clip(chain), source.key()))
+		if err == nil {
+			instance = providerInstance.FactoryFunction.Call([]reflect.Value{instance})[0]
+		}
+	} else {
+		instance, err = c.call(providerInstance.FactoryFunction, nil, providerInstance.parameters, chain)
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
 
-	for index := 0; index < numberOfInputs; index++ {
-		dependencyType := factoryType.In(index)
-		arguments[index] = resolveByType(dependencyType)
+	providerInstance.container.mutex.RLock()
+	decorators := providerInstance.decorators
+	providerInstance.container.mutex.RUnlock()
+
+	for _, decorator := range decorators {
+		instance, err = c.call(decorator.function, []reflect.Value{instance}, decorator.parameters, chain)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return instance, nil
+}
+
+// call resolves the parameters and calls the function with them, after the leading arguments.
+// Functions may return (T, error); a non-nil error is reported with the dependency chain.
+func (c *Container) call(function reflect.Value, leading []reflect.Value, parameters []parameter, chain []providerKey) (reflect.Value, error) {
+	// Resolvers injected for Lazy[T] and func() T stay within the chain until the function returns
+	constructing := new(atomic.Bool)
+	constructing.Store(true)
+	defer constructing.Store(false)
+
+	arguments := make([]reflect.Value, len(leading), len(leading)+len(parameters))
+	copy(arguments, leading)
+	for _, parameter := range parameters {
+		argument, err := c.resolveParameter(parameter, chain, constructing)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		arguments = append(arguments, argument)
 	}
 
-	outputValues := providerInstance.FactoryFunction.Call(arguments)
-	return outputValues[0]
+	outputValues := function.Call(arguments)
+	if len(outputValues) == 2 && !outputValues[1].IsNil() {
+		return reflect.Value{}, newResolutionError(chain, outputValues[1].Interface().(error))
+	}
+	return outputValues[0], nil
+}
+
+// resolveParameter resolves a positional parameter, or fills every field of an In struct.
+func (c *Container) resolveParameter(parameter parameter, chain []providerKey, constructing *atomic.Bool) (reflect.Value, error) {
+	if !parameter.inStruct {
+		return c.resolveDependency(parameter.dependency, chain, constructing)
+	}
+
+	structValue := reflect.New(parameter.inputType).Elem()
+	for _, field := range parameter.fields {
+		fieldValue, err := c.resolveDependency(field.dependency, chain, constructing)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		structValue.Field(field.index).Set(fieldValue)
+	}
+	return structValue, nil
+}
+
+// resolveDependency resolves a group, or a []T without providers of its own, as a slice of every
+// provider of the element type. An optional dependency without providers resolves to its zero value,
+// and Optional[T] parameters receive the value wrapped. Lazy[T], and func() T or func() (T, error)
+// without providers of their own, receive a resolver instead of the instance.
+func (c *Container) resolveDependency(dependency dependency, chain []providerKey, constructing *atomic.Bool) (reflect.Value, error) {
+	key := dependency.key
+
+	var value reflect.Value
+	var err error
+	present := true
+
+	switch {
+	case dependency.lazy:
+		return newLazy(dependency.wrapper, c.resolver(key, chain, constructing)), nil
+	case len(c.lookup(key)) == 0 && key.Name == "" && isResolverFunc(key.Type):
+		return newResolverFunc(key.Type, c.resolver(providerKey{Type: key.Type.Out(0)}, chain, constructing)), nil
+	case key.Group != "":
+		value, err = c.resolveAll(key, chain)
+	case len(c.lookup(key)) == 0 && key.Type.Kind() == reflect.Slice && key.Name == "":
+		value, err = c.resolveAll(providerKey{Type: key.Type.Elem()}, chain)
+	case len(c.lookup(key)) == 0 && dependency.optional:
+		value, present = reflect.Zero(key.Type), false
+	default:
+		value, err = c.resolveKey(key, chain)
+	}
+
+	if err != nil || dependency.wrapper == nil {
+		return value, err
+	}
+	return wrapOptional(dependency.wrapper, value, present), nil
+}
+
+// resolveAll builds every provider for the key into a slice of the key's type, stopping at the first failure.
+func (c *Container) resolveAll(key providerKey, chain []providerKey) (reflect.Value, error) {
+	cyclic := slices.Contains(chain, key)
+	chain = append(slices.Clip(chain), key)
+	if cyclic {
+		return reflect.Value{}, newResolutionError(chain, ErrCycle)
+	}
+
+	providers := c.lookup(key)
+	results := reflect.MakeSlice(reflect.SliceOf(key.Type), len(providers), len(providers))
+	for index, providerInstance := range providers {
+		value, err := c.buildInstance(providerInstance, chain)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		results.Index(index).Set(value)
+	}
+	return results, nil
+}
+
+// resolveAs resolves the provider of T with the given name and converts the instance to T.
+func resolveAs[T any](c *Container, name string) (T, error) {
+	var zero T
+	value, err := c.resolveKey(providerKey{Type: reflect.TypeFor[T](), Name: name}, nil)
+	if err != nil {
+		return zero, err
+	}
+	typed, _ := value.Interface().(T)
+	return typed, nil
+}
+
+// resolveAllAs builds every provider for T, stopping at the first failure.
+func resolveAllAs[T any](c *Container) ([]T, error) {
+	results, err := c.resolveAll(providerKey{Type: reflect.TypeFor[T]()}, nil)
+	if err != nil || results.Len() == 0 {
+		return nil, err
+	}
+	return results.Interface().([]T), nil
+}
+
+// must panics with the error, keeping the panicking API of Resolve and ResolveAll.
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
 }
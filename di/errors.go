@@ -0,0 +1,56 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNoProvider is reported when a type has no registered provider.
+	ErrNoProvider = errors.New("no provider registered")
+	// ErrScopeRequired is reported when a scoped provider is resolved outside of a scope.
+	ErrScopeRequired = errors.New("scoped provider resolved outside of a scope")
+	// ErrScopeClosed is reported when resolving from a scope that was already closed.
+	ErrScopeClosed = errors.New("scope is closed")
+	// ErrCycle is reported when a type depends, directly or not, on itself.
+	ErrCycle = errors.New("dependency cycle detected")
+)
+
+// ResolutionError reports why a type could not be resolved, with the chain of types
+// that led to it, e.g. "di: *UserService -> *UserRepo -> *sql.DB: no provider registered".
+// Named providers and groups are shown with their tag, e.g. "*sql.DB[name=replica]".
+type ResolutionError struct {
+	Chain []reflect.Type // From the requested type down to the one that failed.
+	Tags  []string       // The name or group of each type in Chain as in a `di` tag, e.g. "name=replica".
+	Err   error          // The cause: a sentinel like ErrNoProvider or the error returned by a factory.
+}
+
+func newResolutionError(chain []providerKey, err error) *ResolutionError {
+	resolutionErr := &ResolutionError{
+		Chain: make([]reflect.Type, len(chain)),
+		Tags:  make([]string, len(chain)),
+		Err:   err,
+	}
+	for index, key := range chain {
+		resolutionErr.Chain[index] = key.Type
+		resolutionErr.Tags[index] = key.tag()
+	}
+	return resolutionErr
+}
+
+func (err *ResolutionError) Error() string {
+	names := make([]string, len(err.Chain))
+	for index, chainType := range err.Chain {
+		names[index] = fmt.Sprint(chainType)
+		if index < len(err.Tags) && err.Tags[index] != "" {
+			names[index] += "[" + err.Tags[index] + "]"
+		}
+	}
+	return fmt.Sprintf("di: %s: %v", strings.Join(names, " -> "), err.Err)
+}
+
+func (err *ResolutionError) Unwrap() error {
+	return err.Err
+}
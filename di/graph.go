@@ -0,0 +1,196 @@
+package di
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DependencyGraph describes the providers of a container and the dependencies between them.
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a provider, or a dependency without any provider (Missing).
+type GraphNode struct {
+	ID       string `json:"id"` // The type with its name or group, numbered when several providers share it.
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Lifetime string `json:"lifetime,omitempty"`
+	Built    bool   `json:"built,omitempty"` // A singleton already built.
+	Missing  bool   `json:"missing,omitempty"`
+}
+
+// GraphEdge goes from a provider to a provider it depends on.
+type GraphEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Optional bool   `json:"optional,omitempty"`
+	Deferred bool   `json:"deferred,omitempty"` // Resolved on demand, through Lazy[T] or func() T.
+}
+
+// Graph returns the dependency graph of the default container.
+func Graph() *DependencyGraph {
+	return defaultContainer.Graph()
+}
+
+// Graph returns the dependency graph of the providers visible from the container, with edges taken
+// from the factory and decorator inputs. It calls no factory. Nodes are sorted by ID, so the output
+// is stable and can be checked in; edges are sorted by their ends.
+func (c *Container) Graph() *DependencyGraph {
+	graph := &DependencyGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+
+	// Providers shadowed by a child container (see OverrideOn) are left out
+	var providers []*Provider
+	ids := make(map[*Provider]string)
+	for _, providerInstance := range c.allProviders() {
+		sameKey := c.lookup(providerInstance.key())
+		if slices.Contains(sameKey, providerInstance) {
+			providers = append(providers, providerInstance)
+			ids[providerInstance] = nodeID(providerInstance.key(), sameKey, providerInstance)
+		}
+	}
+
+	missing := make(map[string]string)
+	for _, providerInstance := range providers {
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:       ids[providerInstance],
+			Type:     providerInstance.OutputType.String(),
+			Name:     providerInstance.Name,
+			Group:    providerInstance.Group,
+			Lifetime: providerInstance.providerLifetime().String(),
+			Built:    providerInstance.isBuilt(),
+		})
+
+		if source := providerInstance.source; source != nil {
+			graph.Edges = append(graph.Edges, GraphEdge{From: ids[providerInstance], To: ids[source]})
+			continue
+		}
+
+		// Singletons resolve their dependencies from the container they were registered in
+		resolver := c
+		if providerInstance.IsSingleton {
+			resolver = providerInstance.container
+		}
+
+		for _, dependency := range providerInstance.dependencies() {
+			key, dependencyProviders, collect, deferred := resolver.dependencyProviders(dependency)
+			if len(dependencyProviders) == 0 && !collect {
+				id := nodeID(key, nil, nil)
+				graph.Edges = append(graph.Edges, GraphEdge{From: ids[providerInstance], To: id, Optional: dependency.optional, Deferred: deferred})
+				missing[id] = key.Type.String()
+				continue
+			}
+			for _, dependencyProvider := range dependencyProviders {
+				to, visible := ids[dependencyProvider]
+				if !visible {
+					to = nodeID(dependencyProvider.key(), resolver.lookup(key), dependencyProvider)
+				}
+				graph.Edges = append(graph.Edges, GraphEdge{From: ids[providerInstance], To: to, Optional: dependency.optional, Deferred: deferred})
+			}
+		}
+	}
+
+	for id, missingType := range missing {
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: id, Type: missingType, Missing: true})
+	}
+	slices.SortStableFunc(graph.Nodes, func(a, b GraphNode) int { return strings.Compare(a.ID, b.ID) })
+	slices.SortStableFunc(graph.Edges, func(a, b GraphEdge) int {
+		return cmp.Or(strings.Compare(a.From, b.From), strings.Compare(a.To, b.To))
+	})
+	return graph
+}
+
+// nodeID labels the provider like ResolutionError does, numbering it when several providers share its key.
+func nodeID(key providerKey, sameKey []*Provider, providerInstance *Provider) string {
+	id := key.Type.String()
+	if tag := key.tag(); tag != "" {
+		id += "[" + tag + "]"
+	}
+	if len(sameKey) > 1 {
+		id += "#" + strconv.Itoa(slices.Index(sameKey, providerInstance)+1)
+	}
+	return id
+}
+
+// isBuilt reports whether the provider is a singleton whose instance was already built.
+func (providerInstance *Provider) isBuilt() bool {
+	if !providerInstance.IsSingleton {
+		return false
+	}
+	providerInstance.buildMutex.Lock()
+	defer providerInstance.buildMutex.Unlock()
+	return providerInstance.CachedInstance.IsValid()
+}
+
+// DOT renders the graph in the Graphviz DOT language. Built singletons are bold,
+// missing dependencies dashed and red, optional dependencies dashed and deferred ones dotted.
+func (g *DependencyGraph) DOT() string {
+	var builder strings.Builder
+	builder.WriteString("digraph di {\n\trankdir=LR;\n")
+	for _, node := range g.Nodes {
+		attributes := []string{"label=" + strconv.Quote(node.label("\n"))}
+		switch {
+		case node.Missing:
+			attributes = append(attributes, "style=dashed", "color=red")
+		case node.Built:
+			attributes = append(attributes, "style=bold")
+		}
+		fmt.Fprintf(&builder, "\t%s [%s];\n", strconv.Quote(node.ID), strings.Join(attributes, ", "))
+	}
+	for _, edge := range g.Edges {
+		style := ""
+		switch {
+		case edge.Optional:
+			style = " [style=dashed]"
+		case edge.Deferred:
+			style = " [style=dotted]"
+		}
+		fmt.Fprintf(&builder, "\t%s -> %s%s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To), style)
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Optional and deferred dependencies use dotted arrows.
+func (g *DependencyGraph) Mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+	for index, node := range g.Nodes {
+		ids[node.ID] = "n" + strconv.Itoa(index)
+		label := strings.ReplaceAll(node.label("<br/>"), `"`, "#quot;")
+		fmt.Fprintf(&builder, "\t%s[\"%s\"]\n", ids[node.ID], label)
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if edge.Optional || edge.Deferred {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&builder, "\t%s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+	}
+	return builder.String()
+}
+
+// JSON renders the graph as indented JSON.
+func (g *DependencyGraph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// label describes the node on two lines: its ID, then its lifetime.
+func (node GraphNode) label(lineBreak string) string {
+	switch {
+	case node.Missing:
+		return node.ID + lineBreak + "missing"
+	case node.Built:
+		return node.ID + lineBreak + node.Lifetime + " (built)"
+	default:
+		return node.ID + lineBreak + node.Lifetime
+	}
+}
@@ -0,0 +1,101 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Lazy defers the resolution of T until the first call to Get, for expensive services only needed
+// on some paths. A factory taking a Lazy[T] parameter does not build T, which also lets two services
+// depend on each other as long as neither calls Get while being built.
+type Lazy[T any] struct {
+	state *lazyState[T]
+}
+
+type lazyState[T any] struct {
+	once    sync.Once
+	resolve func() (reflect.Value, error)
+	value   T
+	err     error
+}
+
+// Get resolves T on the first call and returns the same instance afterwards. Panics if T cannot be resolved.
+func (l Lazy[T]) Get() T {
+	return must(l.TryGet())
+}
+
+// TryGet resolves T on the first call and returns the same instance, or the same error, afterwards.
+func (l Lazy[T]) TryGet() (T, error) {
+	if l.state == nil {
+		var zero T
+		return zero, errors.New("di: Lazy was not injected by a container")
+	}
+	l.state.once.Do(func() {
+		var value reflect.Value
+		value, l.state.err = l.state.resolve()
+		if l.state.err == nil {
+			l.state.value, _ = value.Interface().(T)
+		}
+	})
+	return l.state.value, l.state.err
+}
+
+func (Lazy[T]) lazyType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+func (l *Lazy[T]) bind(resolve func() (reflect.Value, error)) {
+	l.state = &lazyState[T]{resolve: resolve}
+}
+
+// lazyWrapper is implemented by every Lazy[T], so parameters can be recognized by reflection.
+type lazyWrapper interface {
+	lazyType() reflect.Type
+}
+
+var lazyWrapperType = reflect.TypeFor[lazyWrapper]()
+
+// isResolverFunc reports whether the type is func() T or func() (T, error).
+func isResolverFunc(funcType reflect.Type) bool {
+	if funcType.Kind() != reflect.Func || funcType.NumIn() != 0 || funcType.IsVariadic() {
+		return false
+	}
+	return funcType.NumOut() == 1 || funcType.NumOut() == 2 && funcType.Out(1) == errorType
+}
+
+// resolver returns a function resolving the key on demand. While the factory receiving it is still
+// running, the function resolves within the current chain, so calling it then still detects cycles.
+func (c *Container) resolver(key providerKey, chain []providerKey, constructing *atomic.Bool) func() (reflect.Value, error) {
+	return func() (reflect.Value, error) {
+		if constructing.Load() {
+			return c.resolveKey(key, chain)
+		}
+		return c.resolveKey(key, nil)
+	}
+}
+
+// newLazy builds the Lazy of the given type around the resolver.
+func newLazy(lazyType reflect.Type, resolve func() (reflect.Value, error)) reflect.Value {
+	lazy := reflect.New(lazyType)
+	lazy.Interface().(interface {
+		bind(func() (reflect.Value, error))
+	}).bind(resolve)
+	return lazy.Elem()
+}
+
+// newResolverFunc builds a func() T or func() (T, error) calling the resolver on every call.
+// func() T panics when T cannot be resolved, like Resolve.
+func newResolverFunc(funcType reflect.Type, resolve func() (reflect.Value, error)) reflect.Value {
+	return reflect.MakeFunc(funcType, func([]reflect.Value) []reflect.Value {
+		value, err := resolve()
+		if funcType.NumOut() == 1 {
+			return []reflect.Value{must(value, err)}
+		}
+		if err != nil {
+			return []reflect.Value{reflect.Zero(funcType.Out(0)), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{value, reflect.Zero(errorType)}
+	})
+}
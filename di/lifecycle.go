@@ -0,0 +1,107 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Starter is implemented by singletons that must run a startup step, such as a consumer
+// connecting to its broker. Start is called by Container.Start in dependency order.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper is implemented by singletons that shut down gracefully, such as an HTTP server.
+// Stop is called by Container.Stop in reverse dependency order. Singletons implementing
+// only io.Closer are closed instead.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// Start starts the singletons of the default container.
+func Start(ctx context.Context) error {
+	return defaultContainer.Start(ctx)
+}
+
+// Stop stops the singletons of the default container.
+func Stop(ctx context.Context) error {
+	return defaultContainer.Stop(ctx)
+}
+
+// Start builds every singleton registered in the container and calls Start on those
+// implementing Starter, dependencies first. It stops at the first failure, stopping
+// what was already built, and returns all errors joined.
+// A hook still running when ctx is done is abandoned and reported with the context error.
+func (c *Container) Start(ctx context.Context) error {
+	for _, providerInstance := range c.allProviders() {
+		if !providerInstance.IsSingleton || providerInstance.container != c {
+			continue
+		}
+		if _, err := providerInstance.buildSingleton(nil); err != nil {
+			return errors.Join(err, c.Stop(ctx))
+		}
+	}
+
+	c.mutex.RLock()
+	built := slices.Clone(c.built)
+	c.mutex.RUnlock()
+
+	for _, providerInstance := range built {
+		starter, ok := providerInstance.CachedInstance.Interface().(Starter)
+		if !ok {
+			continue
+		}
+		if err := runHook(ctx, func() error { return starter.Start(ctx) }); err != nil {
+			err = fmt.Errorf("di: start %v: %w", providerInstance.OutputType, err)
+			return errors.Join(err, c.Stop(ctx))
+		}
+	}
+	return nil
+}
+
+// Stop calls Stop (or Close, for io.Closer) on the singletons built by the container,
+// in reverse dependency order. Every singleton is stopped even if some fail; the errors are joined.
+// Stopped singletons are not stopped again by a later call.
+func (c *Container) Stop(ctx context.Context) error {
+	c.mutex.Lock()
+	built := c.built
+	c.built = nil
+	c.mutex.Unlock()
+
+	var errorList []error
+	for _, providerInstance := range slices.Backward(built) {
+		var hook func() error
+		switch instance := providerInstance.CachedInstance.Interface().(type) {
+		case Stopper:
+			hook = func() error { return instance.Stop(ctx) }
+		case io.Closer:
+			hook = instance.Close
+		default:
+			continue
+		}
+		if err := runHook(ctx, hook); err != nil {
+			errorList = append(errorList, fmt.Errorf("di: stop %v: %w", providerInstance.OutputType, err))
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// runHook runs the hook, giving up when ctx is done so a stuck hook cannot block shutdown.
+func runHook(ctx context.Context, hook func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- hook() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
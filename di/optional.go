@@ -0,0 +1,40 @@
+package di
+
+import "reflect"
+
+// Optional wraps a dependency that may not be registered, such as a metrics exporter.
+// Factories taking an Optional[T] parameter receive it empty instead of failing when T has no provider.
+type Optional[T any] struct {
+	value   T
+	present bool
+}
+
+// Get returns the instance and whether T was registered.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.present
+}
+
+func (Optional[T]) optionalType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+func (o *Optional[T]) set(value reflect.Value) {
+	o.value, _ = value.Interface().(T)
+	o.present = true
+}
+
+// optionalWrapper is implemented by every Optional[T], so parameters can be recognized by reflection.
+type optionalWrapper interface {
+	optionalType() reflect.Type
+}
+
+var optionalWrapperType = reflect.TypeFor[optionalWrapper]()
+
+// wrapOptional builds the Optional of the given type, holding value if present.
+func wrapOptional(wrapperType reflect.Type, value reflect.Value, present bool) reflect.Value {
+	wrapper := reflect.New(wrapperType)
+	if present {
+		wrapper.Interface().(interface{ set(reflect.Value) }).set(value)
+	}
+	return wrapper.Elem()
+}
@@ -0,0 +1,144 @@
+package di
+
+import (
+	"reflect"
+	"slices"
+)
+
+// TB is the part of testing.TB used by Override.
+type TB interface {
+	Cleanup(func())
+}
+
+// Override replaces the providers of T in the default container for the duration of the test,
+// restoring them with tb.Cleanup. It changes the shared container, so tests using it cannot run
+// in parallel; parallel tests should override a Child of the default container with OverrideOn.
+func Override[T any](tb TB, factoryFN any) {
+	tb.Cleanup(OverrideOn[T](defaultContainer, factoryFN))
+}
+
+// OverrideOn replaces the providers of T in the container, e.g. with a fake, and returns a function
+// restoring them. The override keeps the lifetime of the provider it replaces (transient if none).
+// Singletons depending on T, directly or not, are rebuilt in the container on their next resolution,
+// so they receive the override; singletons of a parent container are left untouched.
+func OverrideOn[T any](c *Container, factoryFN any) (restore func()) {
+	key := providerKey{Type: reflect.TypeFor[T]()}
+	factoryValue, _ := checkFactory(factoryFN, key.Type)
+
+	providerLifetime := transient
+	if providers := c.lookup(key); len(providers) > 0 {
+		providerLifetime = providers[0].providerLifetime()
+	}
+
+	replacements := map[providerKey][]*Provider{
+		key: {c.newProvider(factoryValue, providerLifetime, key)},
+	}
+	for affectedKey, providers := range c.dependentSingletons(key) {
+		replacements[affectedKey] = providers
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	previous := make(map[providerKey][]*Provider, len(replacements))
+	for replacedKey, providers := range replacements {
+		if existing, ok := c.providers[replacedKey]; ok {
+			previous[replacedKey] = existing
+		}
+		c.providers[replacedKey] = providers
+	}
+
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for replacedKey := range replacements {
+			if existing, ok := previous[replacedKey]; ok {
+				c.providers[replacedKey] = existing
+			} else {
+				delete(c.providers, replacedKey)
+			}
+		}
+	}
+}
+
+// dependentSingletons finds the singletons visible from the container that depend on key, directly
+// or through other providers, and returns the provider lists of their keys with each of them replaced
+// by an unbuilt copy owned by the container.
+func (c *Container) dependentSingletons(key providerKey) map[providerKey][]*Provider {
+	affected := make(map[*Provider]bool)
+	visiting := make(map[*Provider]bool)
+
+	var dependsOnKey func(providerInstance *Provider) bool
+	dependsOnKey = func(providerInstance *Provider) bool {
+		if result, done := affected[providerInstance]; done {
+			return result
+		}
+		if visiting[providerInstance] {
+			return false // A cycle, reported by Validate or on resolution
+		}
+		visiting[providerInstance] = true
+
+		result := false
+		if source := providerInstance.source; source != nil {
+			result = dependsOnKey(source)
+		}
+		for _, dependency := range providerInstance.dependencies() {
+			dependencyKey, providers, _, _ := c.dependencyProviders(dependency)
+			if dependencyKey == key {
+				result = true
+			}
+			for _, dependencyProvider := range providers {
+				if dependsOnKey(dependencyProvider) {
+					result = true
+				}
+			}
+		}
+		affected[providerInstance] = result
+		return result
+	}
+
+	copies := make(map[*Provider]*Provider)
+	for _, providerInstance := range c.allProviders() {
+		if providerInstance.IsSingleton && providerInstance.key() != key && dependsOnKey(providerInstance) {
+			copies[providerInstance] = c.copyProvider(providerInstance)
+		}
+	}
+
+	replacements := make(map[providerKey][]*Provider)
+	for original, copied := range copies {
+		if source, ok := copies[original.source]; ok {
+			copied.source = source
+		}
+		if _, done := replacements[original.key()]; done {
+			continue
+		}
+		providers := make([]*Provider, 0)
+		for _, visible := range c.lookup(original.key()) {
+			if replacement, ok := copies[visible]; ok {
+				visible = replacement
+			}
+			providers = append(providers, visible)
+		}
+		replacements[original.key()] = providers
+	}
+	return replacements
+}
+
+// copyProvider returns an unbuilt copy of the provider, owned by the container.
+func (c *Container) copyProvider(providerInstance *Provider) *Provider {
+	providerInstance.container.mutex.RLock()
+	defer providerInstance.container.mutex.RUnlock()
+
+	return &Provider{
+		FactoryFunction: providerInstance.FactoryFunction,
+		OutputType:      providerInstance.OutputType,
+		Name:            providerInstance.Name,
+		Group:           providerInstance.Group,
+		IsSingleton:     providerInstance.IsSingleton,
+		IsScoped:        providerInstance.IsScoped,
+		container:       c,
+		parameters:      providerInstance.parameters,
+		source:          providerInstance.source,
+		decorators:      slices.Clone(providerInstance.decorators),
+	}
+}
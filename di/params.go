@@ -0,0 +1,244 @@
+package di
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// In marks a parameter struct. A factory may take a struct embedding In instead of positional
+// parameters; each exported field is resolved by its type and `di` tag:
+//
+//	type RepoParams struct {
+//		di.In
+//		Primary  *sql.DB
+//		Replica  *sql.DB       `di:"name=replica"`
+//		Metrics  *Exporter     `di:"optional"`       // zero value when not registered
+//		Checks   []HealthCheck `di:"group=health"`   // every provider of the group
+//	}
+type In struct{}
+
+// Out marks a result struct. A factory returning a struct embedding Out registers each exported
+// field as its own provider, with the lifetime of the factory and the name or group of its `di` tag:
+//
+//	type Databases struct {
+//		di.Out
+//		Primary *sql.DB     `di:"name=primary"`
+//		Replica *sql.DB     `di:"name=replica"`
+//		Check   HealthCheck `di:"group=health"`
+//	}
+type Out struct{}
+
+var (
+	inType  = reflect.TypeFor[In]()
+	outType = reflect.TypeFor[Out]()
+)
+
+// providerKey identifies a set of providers: a type and an optional name or group.
+type providerKey struct {
+	Type  reflect.Type
+	Name  string
+	Group string
+}
+
+// tag renders the name or group the way it is written in a `di` tag, empty for plain types.
+func (key providerKey) tag() string {
+	switch {
+	case key.Name != "":
+		return "name=" + key.Name
+	case key.Group != "":
+		return "group=" + key.Group
+	default:
+		return ""
+	}
+}
+
+// dependency is a single value a factory needs.
+type dependency struct {
+	key      providerKey
+	optional bool         // Resolves to the zero value when no provider is registered.
+	lazy     bool         // Resolved on the first Get of the Lazy[T] wrapper.
+	wrapper  reflect.Type // The Optional[T] or Lazy[T] type the value is delivered in, if any.
+}
+
+// newDependency describes a dependency on the type, unwrapping Optional[T] into an optional T
+// and Lazy[T] into a lazy T.
+func newDependency(dependencyType reflect.Type) dependency {
+	if dependencyType.Implements(optionalWrapperType) {
+		wrapped := reflect.Zero(dependencyType).Interface().(optionalWrapper).optionalType()
+		return dependency{key: providerKey{Type: wrapped}, optional: true, wrapper: dependencyType}
+	}
+	if dependencyType.Implements(lazyWrapperType) {
+		wrapped := reflect.Zero(dependencyType).Interface().(lazyWrapper).lazyType()
+		return dependency{key: providerKey{Type: wrapped}, lazy: true, wrapper: dependencyType}
+	}
+	return dependency{key: providerKey{Type: dependencyType}}
+}
+
+// parameter is a factory input: a positional dependency, or an In struct with one dependency per field.
+type parameter struct {
+	dependency
+	inputType reflect.Type
+	inStruct  bool
+	fields    []structField
+}
+
+type structField struct {
+	dependency
+	index int
+}
+
+// tagOptions holds the options of a `di` tag, e.g. `di:"name=replica,optional"`.
+type tagOptions struct {
+	name     string
+	group    string
+	optional bool
+}
+
+// parseParameters describes the inputs of the factory. Panics on malformed `di` tags.
+func parseParameters(factoryType reflect.Type) []parameter {
+	parameters := make([]parameter, factoryType.NumIn())
+	for index := range parameters {
+		inputType := factoryType.In(index)
+		parameters[index] = parameter{inputType: inputType, inStruct: isMarkedStruct(inputType, inType)}
+
+		if !parameters[index].inStruct {
+			parameters[index].dependency = newDependency(inputType)
+			continue
+		}
+		for fieldIndex, field := range exportedFields(inputType, inType) {
+			parameters[index].fields = append(parameters[index].fields, structField{
+				dependency: inDependency(inputType, field),
+				index:      fieldIndex,
+			})
+		}
+	}
+	return parameters
+}
+
+// inDependency describes the dependency of an In struct field.
+func inDependency(structType reflect.Type, field reflect.StructField) dependency {
+	options := parseTag(structType, field)
+	dependency := newDependency(field.Type)
+	dependency.key.Name = options.name
+	dependency.optional = dependency.optional || options.optional
+	if options.group != "" {
+		if field.Type.Kind() != reflect.Slice {
+			panic(fmt.Sprintf("di: group field %v.%s must be a slice", structType, field.Name))
+		}
+		dependency.key = providerKey{Type: field.Type.Elem(), Group: options.group}
+	}
+	return dependency
+}
+
+// parseTag reads the `di` tag of an In or Out struct field.
+func parseTag(structType reflect.Type, field reflect.StructField) tagOptions {
+	var options tagOptions
+
+	tag, ok := field.Tag.Lookup("di")
+	if !ok || tag == "" {
+		return options
+	}
+	for option := range strings.SplitSeq(tag, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch name {
+		case "name":
+			options.name = value
+		case "group":
+			options.group = value
+		case "optional":
+			options.optional = true
+		default:
+			panic(fmt.Sprintf("di: unknown option %q in tag of %v.%s", name, structType, field.Name))
+		}
+	}
+	if options.name != "" && options.group != "" {
+		panic(fmt.Sprintf("di: field %v.%s cannot have both a name and a group", structType, field.Name))
+	}
+	return options
+}
+
+// isMarkedStruct reports whether the type is a struct embedding the marker (In or Out).
+func isMarkedStruct(structType reflect.Type, marker reflect.Type) bool {
+	if structType.Kind() != reflect.Struct {
+		return false
+	}
+	for index := range structType.NumField() {
+		field := structType.Field(index)
+		if field.Anonymous && field.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedFields returns the fields of an In or Out struct by index, skipping the marker.
+// Panics on unexported fields, which could never be set or read.
+func exportedFields(structType reflect.Type, marker reflect.Type) iter.Seq2[int, reflect.StructField] {
+	return func(yield func(int, reflect.StructField) bool) {
+		for index := range structType.NumField() {
+			field := structType.Field(index)
+			if field.Type == marker {
+				continue
+			}
+			if !field.IsExported() {
+				panic(fmt.Sprintf("di: field %s of %v must be exported to be injected", field.Name, structType))
+			}
+			if !yield(index, field) {
+				return
+			}
+		}
+	}
+}
+
+// dependencyProviders returns the providers the dependency resolves to from the container, and the key
+// they were found under. Groups, and []T without providers of its own, collect every provider of the
+// element type; everything else resolves to the first provider only. Deferred dependencies (Lazy[T],
+// and func() T or func() (T, error) without providers of their own) are not built with the factory.
+func (c *Container) dependencyProviders(dependency dependency) (key providerKey, providers []*Provider, collect, deferred bool) {
+	key = dependency.key
+	providers = c.lookup(key)
+	collect = key.Group != ""
+	deferred = dependency.lazy
+
+	if len(providers) == 0 && key.Name == "" && !collect {
+		switch {
+		case key.Type.Kind() == reflect.Slice:
+			key = providerKey{Type: key.Type.Elem()}
+			providers = c.lookup(key)
+			collect = true
+		case isResolverFunc(key.Type):
+			key = providerKey{Type: key.Type.Out(0)}
+			providers = c.lookup(key)
+			deferred = true
+		}
+	}
+	if !collect && len(providers) > 1 {
+		providers = providers[:1]
+	}
+	return key, providers, collect, deferred
+}
+
+// dependencies returns every dependency of the provider's factory and decorators, flattening In structs.
+func (providerInstance *Provider) dependencies() []dependency {
+	providerInstance.container.mutex.RLock()
+	parameters := slices.Clone(providerInstance.parameters)
+	for _, decorator := range providerInstance.decorators {
+		parameters = append(parameters, decorator.parameters...)
+	}
+	providerInstance.container.mutex.RUnlock()
+
+	var dependencies []dependency
+	for _, parameter := range parameters {
+		if !parameter.inStruct {
+			dependencies = append(dependencies, parameter.dependency)
+			continue
+		}
+		for _, field := range parameter.fields {
+			dependencies = append(dependencies, field.dependency)
+		}
+	}
+	return dependencies
+}
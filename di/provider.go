@@ -3,25 +3,42 @@ package di
 import (
 	"reflect"
 	"sync"
-)
-
-var (
-	registryMutex    sync.RWMutex
-	providerRegistry = map[reflect.Type][]*Provider{}
+	"sync/atomic"
 )
 
 // Provider holds the necessary information to create and manage an instance.
 type Provider struct {
 	FactoryFunction reflect.Value // The function used to create the instance.
 	OutputType      reflect.Type  // The reflected type of the result.
+	Name            string        // The name given at registration, empty for unnamed providers.
+	Group           string        // The group of a field registered by an Out struct.
 	IsSingleton     bool          // Indicates if it should return the same instance every time.
+	IsScoped        bool          // Indicates if it should return the same instance within a scope.
 	CachedInstance  reflect.Value // Stores the instance if it's a singleton.
+	container       *Container    // The container the provider was registered in.
+	parameters      []parameter   // The factory inputs, parsed at registration.
+	source          *Provider     // The provider of the Out struct this field provider reads from.
+	decorators      []decorator   // Applied in order to every instance built, guarded by the container mutex.
+	buildMutex      sync.Mutex    // Serializes the construction of the singleton instance.
+	built           atomic.Bool   // Set once the singleton instance is cached, to skip the cycle check.
+}
+
+// key returns the key the provider is registered under.
+func (providerInstance *Provider) key() providerKey {
+	return providerKey{Type: providerInstance.OutputType, Name: providerInstance.Name, Group: providerInstance.Group}
 }
 
+// lifetime selects how instances of a provider are shared.
+type lifetime int
+
+const (
+	transient lifetime = iota
+	singleton
+	scoped
+)
+
 // Reset clears all registered providers.
 // Primarily used for unit tests to ensure isolation.
 func Reset() {
-	registryMutex.Lock()
-	defer registryMutex.Unlock()
-	providerRegistry = make(map[reflect.Type][]*Provider)
+	defaultContainer.Reset()
 }
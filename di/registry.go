@@ -6,8 +6,29 @@ import (
 )
 
 // registerProvider handles the low-level logic of adding a factory to the registry.
-// It performs basic validation on return types and assignability.
-func registerProvider(factoryFN any, isSingleton bool, asType reflect.Type) {
+func (c *Container) registerProvider(factoryFN any, providerLifetime lifetime, asType reflect.Type, name string) {
+	factoryValue, outputType := checkFactory(factoryFN, asType)
+
+	isOut := asType == nil && name == "" && isMarkedStruct(outputType, outType)
+	if isOut {
+		// Check every tag first, so a malformed one registers nothing
+		for _, field := range exportedFields(outputType, outType) {
+			if parseTag(outputType, field).optional {
+				panic(fmt.Sprintf("di: field %v.%s of an Out struct cannot be optional", outputType, field.Name))
+			}
+		}
+	}
+
+	providerInstance := c.addProvider(factoryValue, providerLifetime, providerKey{Type: outputType, Name: name})
+
+	if isOut {
+		c.registerOutFields(providerInstance, providerLifetime)
+	}
+}
+
+// checkFactory performs basic validation on return types and assignability,
+// returning the factory and the type it provides.
+func checkFactory(factoryFN any, asType reflect.Type) (reflect.Value, reflect.Type) {
 	if factoryFN == nil {
 		panic("di: nil factory function provided")
 	}
@@ -19,8 +40,11 @@ func registerProvider(factoryFN any, isSingleton bool, asType reflect.Type) {
 		panic("di: factory must be a function")
 	}
 
-	if factoryType.NumOut() != 1 {
-		panic("di: factory function must return exactly one value")
+	switch {
+	case factoryType.NumOut() == 1:
+	case factoryType.NumOut() == 2 && factoryType.Out(1) == errorType:
+	default:
+		panic("di: factory function must return exactly one value, or a value and an error")
 	}
 
 	outputType := factoryType.Out(0)
@@ -32,14 +56,46 @@ func registerProvider(factoryFN any, isSingleton bool, asType reflect.Type) {
 		}
 		outputType = asType
 	}
+	return factoryValue, outputType
+}
+
+// registerOutFields registers a provider for each field of an Out struct. Each one reads its field
+// from the struct built by the source provider, sharing its lifetime.
+func (c *Container) registerOutFields(source *Provider, providerLifetime lifetime) {
+	structType := source.OutputType
+	for fieldIndex, field := range exportedFields(structType, outType) {
+		options := parseTag(structType, field)
+
+		extractorType := reflect.FuncOf([]reflect.Type{structType}, []reflect.Type{field.Type}, false)
+		extractor := reflect.MakeFunc(extractorType, func(arguments []reflect.Value) []reflect.Value {
+			return []reflect.Value{arguments[0].Field(fieldIndex)}
+		})
+
+		fieldProvider := c.addProvider(extractor, providerLifetime, providerKey{Type: field.Type, Name: options.name, Group: options.group})
+		fieldProvider.source = source
+	}
+}
 
-	providerInstance := &Provider{
+// addProvider stores a provider for the factory under the key.
+func (c *Container) addProvider(factoryValue reflect.Value, providerLifetime lifetime, key providerKey) *Provider {
+	providerInstance := c.newProvider(factoryValue, providerLifetime, key)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.providers[key] = append(c.providers[key], providerInstance)
+	return providerInstance
+}
+
+// newProvider creates a provider owned by the container, without registering it.
+func (c *Container) newProvider(factoryValue reflect.Value, providerLifetime lifetime, key providerKey) *Provider {
+	return &Provider{
 		FactoryFunction: factoryValue,
-		OutputType:      outputType,
-		IsSingleton:     isSingleton,
+		OutputType:      key.Type,
+		Name:            key.Name,
+		Group:           key.Group,
+		IsSingleton:     providerLifetime == singleton,
+		IsScoped:        providerLifetime == scoped,
+		container:       c,
+		parameters:      parseParameters(factoryValue.Type()),
 	}
-
-	registryMutex.Lock()
-	defer registryMutex.Unlock()
-	providerRegistry[outputType] = append(providerRegistry[outputType], providerInstance)
 }
@@ -0,0 +1,101 @@
+package di
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// scopeState caches the scoped instances of a scope, in creation order so they are released in reverse.
+type scopeState struct {
+	instances map[*Provider]*scopedInstance
+	order     []*scopedInstance
+	closed    bool
+}
+
+type scopedInstance struct {
+	once  sync.Once
+	value reflect.Value
+	err   error
+}
+
+// NewScope creates a child scope of the default container.
+func NewScope() *Container {
+	return defaultContainer.NewScope()
+}
+
+// NewScope creates a child scope, e.g. one per HTTP request. Scoped providers resolved from it
+// return one instance for the lifetime of the scope; everything else falls back to the parent.
+// Call Close to release the scoped instances.
+func (c *Container) NewScope() *Container {
+	return &Container{
+		providers: make(map[providerKey][]*Provider),
+		parent:    c,
+		scope:     &scopeState{instances: make(map[*Provider]*scopedInstance)},
+	}
+}
+
+// Close releases the scoped instances, calling Close on those implementing io.Closer
+// in reverse creation order. The scope cannot be used afterwards. It is a no-op on non-scope containers.
+func (c *Container) Close() error {
+	if c.scope == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	order := c.scope.order
+	c.scope.instances = nil
+	c.scope.order = nil
+	c.scope.closed = true
+	c.mutex.Unlock()
+
+	var errorList []error
+	for _, instance := range slices.Backward(order) {
+		if !instance.value.IsValid() || !instance.value.CanInterface() {
+			continue
+		}
+		if closer, ok := instance.value.Interface().(io.Closer); ok {
+			errorList = append(errorList, closer.Close())
+		}
+	}
+	return errors.Join(errorList...)
+}
+
+// buildScoped returns the instance of the provider cached in this scope, building it on first use.
+// A failed build is not cached, so the next resolution tries again.
+func (c *Container) buildScoped(providerInstance *Provider, chain []providerKey) (reflect.Value, error) {
+	if c.scope == nil {
+		return reflect.Value{}, newResolutionError(chain, ErrScopeRequired)
+	}
+
+	c.mutex.Lock()
+	if c.scope.closed {
+		c.mutex.Unlock()
+		return reflect.Value{}, newResolutionError(chain, ErrScopeClosed)
+	}
+	instance, exists := c.scope.instances[providerInstance]
+	if !exists {
+		instance = &scopedInstance{}
+		c.scope.instances[providerInstance] = instance
+		c.scope.order = append(c.scope.order, instance)
+	}
+	c.mutex.Unlock()
+
+	// The factory runs without holding the scope lock, so it can resolve other scoped dependencies
+	instance.once.Do(func() {
+		instance.value, instance.err = c.callFactoryWithDependencies(providerInstance, chain)
+		if instance.err != nil {
+			c.mutex.Lock()
+			if c.scope.instances[providerInstance] == instance {
+				delete(c.scope.instances, providerInstance)
+			}
+			c.scope.order = slices.DeleteFunc(c.scope.order, func(candidate *scopedInstance) bool {
+				return candidate == instance
+			})
+			c.mutex.Unlock()
+		}
+	})
+	return instance.value, instance.err
+}
@@ -0,0 +1,141 @@
+package di
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrLifetimeMismatch is reported by Validate when a singleton depends on a shorter-lived service.
+var ErrLifetimeMismatch = errors.New("lifetime mismatch")
+
+func (l lifetime) String() string {
+	switch l {
+	case singleton:
+		return "singleton"
+	case scoped:
+		return "scoped"
+	default:
+		return "transient"
+	}
+}
+
+// providerLifetime returns the lifetime the provider was registered with.
+func (providerInstance *Provider) providerLifetime() lifetime {
+	switch {
+	case providerInstance.IsSingleton:
+		return singleton
+	case providerInstance.IsScoped:
+		return scoped
+	default:
+		return transient
+	}
+}
+
+// Validate checks the dependency graph of the default container without calling any factory.
+func Validate() error {
+	return defaultContainer.Validate()
+}
+
+// Validate checks that every registered provider can be resolved, without calling any factory.
+// It reports all missing providers, dependency cycles and lifetime mismatches
+// (a singleton depending on a transient or scoped service) in one joined error of *ResolutionError.
+func (c *Container) Validate() error {
+	validator := &graphValidator{
+		root:  c,
+		state: make(map[*Provider]visitState),
+	}
+	for _, providerInstance := range c.allProviders() {
+		validator.visit(providerInstance, nil)
+	}
+	return errors.Join(validator.errorList...)
+}
+
+type visitState int
+
+const (
+	unvisited visitState = iota
+	visiting
+	visited
+)
+
+// graphValidator walks the providers depth-first, reporting each problem once.
+type graphValidator struct {
+	root      *Container
+	state     map[*Provider]visitState
+	errorList []error
+}
+
+func (validator *graphValidator) visit(providerInstance *Provider, chain []providerKey) {
+	chain = append(slices.Clip(chain), providerInstance.key())
+
+	switch validator.state[providerInstance] {
+	case visiting:
+		validator.report(chain, ErrCycle)
+		return
+	case visited:
+		return
+	}
+	validator.state[providerInstance] = visiting
+	defer func() { validator.state[providerInstance] = visited }()
+
+	// Singletons resolve their dependencies from the container they were registered in
+	resolver := validator.root
+	if providerInstance.IsSingleton {
+		resolver = providerInstance.container
+	}
+
+	if providerInstance.source != nil {
+		validator.visit(providerInstance.source, chain)
+		return
+	}
+
+	for _, dependency := range providerInstance.dependencies() {
+		key, providers, collect, deferred := resolver.dependencyProviders(dependency)
+
+		dependencyChain := append(slices.Clip(chain), key)
+		if len(providers) == 0 {
+			if !dependency.optional && !collect {
+				validator.report(dependencyChain, ErrNoProvider)
+			}
+			continue
+		}
+
+		// Deferred dependencies are built after the provider, so they cannot close a cycle or be captured
+		if deferred {
+			continue
+		}
+		for _, dependencyProvider := range providers {
+			if providerInstance.IsSingleton && !dependencyProvider.IsSingleton {
+				validator.report(dependencyChain, fmt.Errorf("%w: singleton depends on %s", ErrLifetimeMismatch, dependencyProvider.providerLifetime()))
+			}
+			validator.visit(dependencyProvider, chain)
+		}
+	}
+}
+
+func (validator *graphValidator) report(chain []providerKey, err error) {
+	validator.errorList = append(validator.errorList, newResolutionError(chain, err))
+}
+
+// allProviders returns the providers visible from the container, sorted by type, name and group so
+// reports are stable. Providers sharing a key keep their registration order, the container's first.
+func (c *Container) allProviders() []*Provider {
+	var providers []*Provider
+	for container := c; container != nil; container = container.parent {
+		container.mutex.RLock()
+		for _, typeProviders := range container.providers {
+			providers = append(providers, typeProviders...)
+		}
+		container.mutex.RUnlock()
+	}
+	slices.SortStableFunc(providers, func(a, b *Provider) int {
+		return cmp.Or(
+			cmp.Compare(a.OutputType.String(), b.OutputType.String()),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Group, b.Group),
+		)
+	})
+	return providers
+}
@@ -0,0 +1,128 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrRequired is reported when a variable tagged `required:"true"` is not set and has no default.
+var ErrRequired = errors.New("required variable is not set")
+
+// VarError reports an environment variable that is missing or cannot be converted.
+type VarError struct {
+	Key   string       // The variable name, including any prefix.
+	Value string       // The raw value, empty when the variable is missing.
+	Type  reflect.Type // The type the value should convert to.
+	Err   error        // ErrRequired, or the conversion error.
+}
+
+func (err *VarError) Error() string {
+	if errors.Is(err.Err, ErrRequired) {
+		return fmt.Sprintf("env: %s: %v", err.Key, err.Err)
+	}
+	return fmt.Sprintf("env: invalid value %q for %s (%v): %v", err.Value, err.Key, err.Type, err.Err)
+}
+
+func (err *VarError) Unwrap() error {
+	return err.Err
+}
+
+// Parse returns a T populated from the environment. See Bind for the supported tags.
+func Parse[T any]() (T, error) {
+	var target T
+	err := Bind(&target)
+	return target, err
+}
+
+// Bind populates the struct pointed to by target from the environment, using the field tags:
+//
+//	type Config struct {
+//		Port    int           `env:"PORT" default:"8080"`
+//		Timeout time.Duration `env:"TIMEOUT" required:"true"`
+//		DB      DBConfig      `envPrefix:"DB_"` // DBConfig fields read DB_HOST, DB_USER...
+//	}
+//
+// Nested structs (or pointers to structs) without an env tag are bound recursively, their keys
+// prefixed by envPrefix. Fields whose variable is missing and have no default are left untouched.
+// Bind reports every missing required or invalid variable at once, as a joined error of *VarError.
+func Bind(target any) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env: Bind target must be a non-nil pointer to a struct, got %T", target)
+	}
+
+	var errorList []error
+	bindStruct(value.Elem(), "", &errorList)
+	return errors.Join(errorList...)
+}
+
+func bindStruct(structValue reflect.Value, prefix string, errorList *[]error) {
+	structType := structValue.Type()
+
+	for index := range structType.NumField() {
+		field := structType.Field(index)
+		fieldValue := structValue.Field(index)
+
+		key, tagged := field.Tag.Lookup("env")
+		if !tagged {
+			if nested, ok := nestedStruct(field, fieldValue); ok {
+				bindStruct(nested, prefix+field.Tag.Get("envPrefix"), errorList)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			*errorList = append(*errorList, fmt.Errorf("env: field %s of %v must be exported to be bound", field.Name, structType))
+			continue
+		}
+		key = prefix + key
+
+		raw, found := lookupEnv(key)
+		if !found || strings.TrimSpace(raw) == "" {
+			defaultValue, hasDefault := field.Tag.Lookup("default")
+			switch {
+			case hasDefault:
+				raw = defaultValue
+			case field.Tag.Get("required") == "true":
+				*errorList = append(*errorList, &VarError{Key: key, Type: field.Type, Err: ErrRequired})
+				continue
+			default:
+				continue
+			}
+		}
+
+		converted, err := convertString(raw, field.Type)
+		if err != nil {
+			*errorList = append(*errorList, &VarError{Key: key, Value: raw, Type: field.Type, Err: err})
+			continue
+		}
+		fieldValue.Set(converted)
+	}
+}
+
+// nestedStruct returns the struct to bind recursively for an untagged field: a struct, or a pointer
+// to a struct (allocated when nil). Types converted from a single value, like time.Time, are not nested.
+func nestedStruct(field reflect.StructField, fieldValue reflect.Value) (reflect.Value, bool) {
+	if !field.IsExported() {
+		return reflect.Value{}, false
+	}
+
+	switch {
+	case field.Type.Kind() == reflect.Struct && !isScalar(field.Type):
+		return fieldValue, true
+	case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct && !isScalar(field.Type.Elem()):
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.New(field.Type.Elem()))
+		}
+		return fieldValue.Elem(), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// isScalar reports whether the struct type is converted from a single variable.
+func isScalar(structType reflect.Type) bool {
+	return structType == reflect.TypeFor[time.Time]()
+}
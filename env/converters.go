@@ -10,39 +10,46 @@ import (
 
 func convertStringToType[T any](raw string) (T, error) {
 	var zero T
-	targetType := reflect.TypeFor[T]()
+	value, err := convertString(raw, reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	return value.Interface().(T), nil
+}
 
+// convertString converts raw to targetType, for callers that only know the type at runtime.
+func convertString(raw string, targetType reflect.Type) (reflect.Value, error) {
 	// Special Types
 	switch targetType {
 	case reflect.TypeFor[json.RawMessage]():
 		if !json.Valid([]byte(raw)) {
-			return zero, fmt.Errorf("env: invalid JSON")
+			return reflect.Value{}, fmt.Errorf("env: invalid JSON")
 		}
-		return any(json.RawMessage(raw)).(T), nil
+		return reflect.ValueOf(json.RawMessage(raw)), nil
 	case reflect.TypeFor[time.Time]():
 		t, err := parseTime(raw)
-		return any(t).(T), err
+		return reflect.ValueOf(t), err
 	case reflect.TypeFor[time.Duration]():
 		d, err := time.ParseDuration(raw)
-		return any(d).(T), err
+		return reflect.ValueOf(d), err
 	}
 
 	// Basic Kinds
 	switch targetType.Kind() {
 	case reflect.String:
-		return any(raw).(T), nil
+		return reflect.ValueOf(raw).Convert(targetType), nil
 	case reflect.Bool:
 		v, err := strconv.ParseBool(raw)
-		return any(v).(T), err
+		return reflect.ValueOf(v).Convert(targetType), err
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		v, err := strconv.ParseInt(raw, 10, targetType.Bits())
-		return reflect.ValueOf(v).Convert(targetType).Interface().(T), err
+		return reflect.ValueOf(v).Convert(targetType), err
 	case reflect.Float32, reflect.Float64:
 		v, err := strconv.ParseFloat(raw, targetType.Bits())
-		return reflect.ValueOf(v).Convert(targetType).Interface().(T), err
+		return reflect.ValueOf(v).Convert(targetType), err
 	}
 
-	return zero, fmt.Errorf("env: unsupported type %v", targetType)
+	return reflect.Value{}, fmt.Errorf("env: unsupported type %v", targetType)
 }
 
 func parseTime(raw string) (time.Time, error) {
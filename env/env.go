@@ -2,6 +2,7 @@ package env
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 )
@@ -50,6 +51,35 @@ func Get[T any](key string, optionalDefault ...T) T {
 	return converted
 }
 
+// Lookup retrieves an environment variable and converts it to T, telling a missing variable
+// (found is false) from a malformed one (a *VarError naming the key, the raw value and T).
+// Blank values count as missing, like in Get.
+func Lookup[T any](key string) (value T, found bool, err error) {
+	raw, found := lookupEnv(key)
+	if !found || strings.TrimSpace(raw) == "" {
+		return value, false, nil
+	}
+
+	value, err = convertStringToType[T](raw)
+	if err != nil {
+		return value, true, &VarError{Key: key, Value: raw, Type: reflect.TypeFor[T](), Err: err}
+	}
+	return value, true, nil
+}
+
+// MustGet retrieves an environment variable and converts it to T.
+// Panics with a *VarError if the variable is missing (ErrRequired) or malformed.
+func MustGet[T any](key string) T {
+	value, found, err := Lookup[T](key)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		panic(&VarError{Key: key, Type: reflect.TypeFor[T](), Err: ErrRequired})
+	}
+	return value
+}
+
 func lookupEnv(key string) (string, bool) {
 	envMutex.RLock()
 	defer envMutex.RUnlock()
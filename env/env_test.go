@@ -1,8 +1,11 @@
 package env
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEnv_LoadAndExpand(t *testing.T) {
@@ -38,3 +41,110 @@ func TestEnv_LoadAndExpand(t *testing.T) {
 		}
 	})
 }
+
+type DBConfig struct {
+	Host string `env:"HOST" default:"localhost"`
+	Port int    `env:"PORT" required:"true"`
+}
+
+type AppConfig struct {
+	Name    string        `env:"APP_NAME"`
+	Debug   bool          `env:"APP_DEBUG" default:"false"`
+	Timeout time.Duration `env:"APP_TIMEOUT" required:"true"`
+	Started time.Time     `env:"APP_STARTED"`
+	DB      DBConfig      `envPrefix:"DB_"`
+	Replica *DBConfig     `envPrefix:"REPLICA_"`
+}
+
+func TestEnv_Bind(t *testing.T) {
+	t.Run("Populate nested structs with defaults", func(t *testing.T) {
+		t.Setenv("APP_NAME", "orders")
+		t.Setenv("APP_TIMEOUT", "5s")
+		t.Setenv("APP_STARTED", "2024-01-02")
+		t.Setenv("DB_PORT", "5432")
+		t.Setenv("REPLICA_HOST", "replica")
+		t.Setenv("REPLICA_PORT", "5433")
+
+		cfg, err := Parse[AppConfig]()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cfg.Name != "orders" || cfg.Debug || cfg.Timeout != 5*time.Second || cfg.Started.Year() != 2024 {
+			t.Errorf("Unexpected config: %+v", cfg)
+		}
+		if cfg.DB != (DBConfig{Host: "localhost", Port: 5432}) || *cfg.Replica != (DBConfig{Host: "replica", Port: 5433}) {
+			t.Errorf("Unexpected nested configs: %+v and %+v", cfg.DB, cfg.Replica)
+		}
+	})
+
+	t.Run("Report every missing or invalid variable", func(t *testing.T) {
+		t.Setenv("APP_DEBUG", "maybe")
+		t.Setenv("APP_TIMEOUT", "5 s")
+		t.Setenv("DB_PORT", "")
+		t.Setenv("REPLICA_PORT", "5433")
+
+		var cfg AppConfig
+		err := Bind(&cfg)
+		if !errors.Is(err, ErrRequired) {
+			t.Errorf("Expected ErrRequired, got %v", err)
+		}
+		expected := []string{
+			`env: invalid value "maybe" for APP_DEBUG (bool)`,
+			`env: invalid value "5 s" for APP_TIMEOUT (time.Duration)`,
+			`env: DB_PORT: required variable is not set`,
+		}
+		for _, message := range expected {
+			if err == nil || !strings.Contains(err.Error(), message) {
+				t.Errorf("Expected the error to contain %q, got %v", message, err)
+			}
+		}
+	})
+
+	t.Run("Reject non-struct targets", func(t *testing.T) {
+		var port int
+		if err := Bind(&port); err == nil {
+			t.Error("Expected an error for a non-struct target")
+		}
+	})
+}
+
+func TestEnv_Lookup(t *testing.T) {
+	t.Setenv("LOOKUP_TIMEOUT", "5 s")
+	t.Setenv("LOOKUP_RETRIES", "3")
+
+	t.Run("Distinguish missing from malformed", func(t *testing.T) {
+		if _, found, err := Lookup[int]("LOOKUP_MISSING"); found || err != nil {
+			t.Errorf("Expected a missing variable without error, got %v and %v", found, err)
+		}
+
+		_, found, err := Lookup[time.Duration]("LOOKUP_TIMEOUT")
+		var varErr *VarError
+		if !found || !errors.As(err, &varErr) {
+			t.Fatalf("Expected a VarError for a malformed variable, got %v", err)
+		}
+		expected := `env: invalid value "5 s" for LOOKUP_TIMEOUT (time.Duration)`
+		if !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected %q, got %q", expected, err.Error())
+		}
+
+		if retries, found, err := Lookup[int]("LOOKUP_RETRIES"); retries != 3 || !found || err != nil {
+			t.Errorf("Expected 3, got %d, %v and %v", retries, found, err)
+		}
+	})
+
+	t.Run("Panic on missing or malformed variables", func(t *testing.T) {
+		if MustGet[int]("LOOKUP_RETRIES") != 3 {
+			t.Error("Expected 3")
+		}
+		for _, key := range []string{"LOOKUP_MISSING", "LOOKUP_TIMEOUT"} {
+			func() {
+				defer func() {
+					if err, ok := recover().(*VarError); !ok || err.Key != key {
+						t.Errorf("Expected a VarError for %s, got %v", key, err)
+					}
+				}()
+				MustGet[time.Duration](key)
+			}()
+		}
+	})
+}
@@ -5,6 +5,7 @@
 package v
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
@@ -160,6 +161,9 @@ func WithAdditionalDateLayouts(layouts ...string) Option {
 	return schema.WithAdditionalDateLayouts(layouts...)
 }
 
+// ErrNoSchema is returned by Validate when no schema is registered for the requested type.
+var ErrNoSchema = errors.New("no schema registered")
+
 // Register registers a schema in the global registry for its output type.
 func Register(schemaValue AnySchema) {
 	registry.Register(schemaValue)
@@ -250,7 +254,7 @@ func Validate[T any](input any, optionList ...Option) (T, error) {
 	schemaValue, ok := registry.LookupTyped[T]()
 	if !ok {
 		outputType := reflect.TypeFor[T]()
-		return zero, fmt.Errorf("no schema registered for %s", outputType.String())
+		return zero, fmt.Errorf("%w for %s", ErrNoSchema, outputType.String())
 	}
 
 	options := schema.ApplyOptions(optionList...)
@@ -269,6 +273,18 @@ func Validate[T any](input any, optionList ...Option) (T, error) {
 	return typed, nil
 }
 
+// ValidateType validates the input against the registered schema for outputType.
+// It is the reflection-based counterpart of Validate, for callers that only know the type at runtime.
+func ValidateType(outputType reflect.Type, input any, optionList ...Option) (any, error) {
+	schemaValue, ok := registry.Lookup(outputType)
+	if !ok {
+		return nil, fmt.Errorf("%w for %v", ErrNoSchema, outputType)
+	}
+
+	options := schema.ApplyOptions(optionList...)
+	return schemaValue.ValidateAny(input, options)
+}
+
 // Fluent API Types
 
 // FieldBuilder is the entry point for defining field rules.